@@ -0,0 +1,33 @@
+package bookmarks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxCollectionsPerUser is how many collections a user can have.
+	MaxCollectionsPerUser = 100
+	// MaxNameLength is the longest collection name, in characters.
+	MaxNameLength = 50
+)
+
+var (
+	ErrEmptyName          = errors.New("collection name can't be empty")
+	ErrNameTooLong        = fmt.Errorf("collection name must be at most %d characters", MaxNameLength)
+	ErrTooManyCollections = fmt.Errorf("you can have at most %d collections", MaxCollectionsPerUser)
+)
+
+// NormalizeName trims a collection name and checks it.
+func NormalizeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrEmptyName
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrNameTooLong
+	}
+	return name, nil
+}
@@ -0,0 +1,30 @@
+package bookmarks
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ValidateCollections struct {
+	suite.Suite
+}
+
+func (s *ValidateCollections) TestNormalizeName() {
+	name, err := NormalizeName("  Recipes ")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "Recipes", name)
+
+	_, err = NormalizeName(" \t")
+	assert.ErrorIs(s.T(), err, ErrEmptyName)
+
+	_, err = NormalizeName(strings.Repeat("ü", MaxNameLength+1))
+	assert.ErrorIs(s.T(), err, ErrNameTooLong)
+	_, err = NormalizeName(strings.Repeat("ü", MaxNameLength))
+	assert.NoError(s.T(), err)
+}
+
+func TestValidateCollections(t *testing.T) {
+	suite.Run(t, new(ValidateCollections))
+}
@@ -7,21 +7,86 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const cancelScheduledChirp = `-- name: CancelScheduledChirp :execrows
+delete from chirps where id = $1 and user_id = $2 and publish_at is not null
+`
+
+type CancelScheduledChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CancelScheduledChirp(ctx context.Context, arg CancelScheduledChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelScheduledChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createChirp = `-- name: CreateChirp :one
-insert into chirps (id, created_at, updated_at, body, user_id) values (gen_random_uuid(), NOW(), NOW(), $1, $2) returning id, created_at, updated_at, body, user_id
+insert into chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, visibility) values (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5) returning id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+	IsRechirp     bool
+	Visibility    string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.QuotedChirpID,
+		arg.IsRechirp,
+		arg.Visibility,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextPublishAttemptAt,
+	)
+	return i, err
+}
+
+const createScheduledChirp = `-- name: CreateScheduledChirp :one
+insert into chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility) values (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, false, $4, $5) returning id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at
+`
+
+type CreateScheduledChirpParams struct {
+	Body          string
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+	PublishAt     sql.NullTime
+	Visibility    string
+}
+
+func (q *Queries) CreateScheduledChirp(ctx context.Context, arg CreateScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledChirp,
+		arg.Body,
+		arg.UserID,
+		arg.QuotedChirpID,
+		arg.PublishAt,
+		arg.Visibility,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -29,12 +94,18 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextPublishAttemptAt,
 	)
 	return i, err
 }
 
 const deleteChirpById = `-- name: DeleteChirpById :exec
-delete from chirps where id = $1 returning id, created_at, updated_at, body, user_id
+delete from chirps where id = $1 returning id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at
 `
 
 func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
@@ -42,8 +113,94 @@ func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirpsOfChirp = `-- name: DeleteRechirpsOfChirp :exec
+delete from chirps where quoted_chirp_id = $1 and is_rechirp = true
+`
+
+func (q *Queries) DeleteRechirpsOfChirp(ctx context.Context, quotedChirpID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirpsOfChirp, quotedChirpID)
+	return err
+}
+
+const postponeScheduledChirp = `-- name: PostponeScheduledChirp :exec
+update chirps set publish_attempts = publish_attempts + 1, next_publish_attempt_at = NOW() + make_interval(secs => $1::integer)
+where id = $2 and publish_at is not null
+`
+
+type PostponeScheduledChirpParams struct {
+	DelaySeconds int32
+	ID           uuid.UUID
+}
+
+func (q *Queries) PostponeScheduledChirp(ctx context.Context, arg PostponeScheduledChirpParams) error {
+	_, err := q.db.ExecContext(ctx, postponeScheduledChirp, arg.DelaySeconds, arg.ID)
+	return err
+}
+
+const publishDueChirp = `-- name: PublishDueChirp :one
+update chirps set publish_at = null, publish_attempts = 0, next_publish_attempt_at = null, created_at = NOW(), updated_at = NOW()
+where id = (
+    select id from chirps
+    where publish_at <= NOW() and (next_publish_attempt_at is null or next_publish_attempt_at <= NOW())
+    order by publish_at
+    limit 1
+    for update skip locked
+)
+returning id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at
+`
+
+func (q *Queries) PublishDueChirp(ctx context.Context) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, publishDueChirp)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextPublishAttemptAt,
+	)
+	return i, err
+}
+
+const rescheduleChirp = `-- name: RescheduleChirp :one
+update chirps set publish_at = $3, publish_attempts = 0, next_publish_attempt_at = null, updated_at = NOW()
+where id = $1 and user_id = $2 and publish_at is not null
+returning id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at
+`
+
+type RescheduleChirpParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	PublishAt sql.NullTime
+}
+
+func (q *Queries) RescheduleChirp(ctx context.Context, arg RescheduleChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, rescheduleChirp, arg.ID, arg.UserID, arg.PublishAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextPublishAttemptAt,
+	)
+	return i, err
+}
+
 const retrieveChirpById = `-- name: RetrieveChirpById :one
-select id, created_at, updated_at, body, user_id from chirps where id = $1
+select id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at from chirps where id = $1 and publish_at is null
 `
 
 func (q *Queries) RetrieveChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -55,16 +212,50 @@ func (q *Queries) RetrieveChirpById(ctx context.Context, id uuid.UUID) (Chirp, e
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextPublishAttemptAt,
+	)
+	return i, err
+}
+
+const retrieveChirpOrScheduledById = `-- name: RetrieveChirpOrScheduledById :one
+select id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at from chirps where id = $1
+`
+
+func (q *Queries) RetrieveChirpOrScheduledById(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, retrieveChirpOrScheduledById, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextPublishAttemptAt,
 	)
 	return i, err
 }
 
 const retrieveChirps = `-- name: RetrieveChirps :many
-select id, created_at, updated_at, body, user_id from chirps order by created_at asc
+select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.publish_at, chirps.visibility, chirps.publish_attempts, chirps.next_publish_attempt_at from chirps join users on users.id = chirps.user_id
+where chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = $1::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = $1::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = $1::uuid))
+  and chirps.user_id not in (select muted_id from mutes where muter_id = $1::uuid)
+  and (chirps.visibility = 'public' or chirps.user_id = $1::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = $1::uuid)))
+order by chirps.created_at asc
 `
 
-func (q *Queries) RetrieveChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, retrieveChirps)
+func (q *Queries) RetrieveChirps(ctx context.Context, viewerID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirps, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +269,12 @@ func (q *Queries) RetrieveChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.NextPublishAttemptAt,
 		); err != nil {
 			return nil, err
 		}
@@ -93,11 +290,20 @@ func (q *Queries) RetrieveChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const retrieveChirpsByAuthor = `-- name: RetrieveChirpsByAuthor :many
-select id, created_at, updated_at, body, user_id from chirps where user_id = $1 order by created_at asc
+select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.publish_at, chirps.visibility, chirps.publish_attempts, chirps.next_publish_attempt_at from chirps join users on users.id = chirps.user_id
+where chirps.user_id = $1 and chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = $2::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = $2::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = $2::uuid))
+  and (chirps.visibility = 'public' or chirps.user_id = $2::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = $2::uuid)))
+order by chirps.created_at asc
 `
 
-func (q *Queries) RetrieveChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, retrieveChirpsByAuthor, userID)
+type RetrieveChirpsByAuthorParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) RetrieveChirpsByAuthor(ctx context.Context, arg RetrieveChirpsByAuthorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirpsByAuthor, arg.UserID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +317,12 @@ func (q *Queries) RetrieveChirpsByAuthor(ctx context.Context, userID uuid.UUID)
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.NextPublishAttemptAt,
 		); err != nil {
 			return nil, err
 		}
@@ -126,11 +338,67 @@ func (q *Queries) RetrieveChirpsByAuthor(ctx context.Context, userID uuid.UUID)
 }
 
 const retrieveChirpsByAuthorDesc = `-- name: RetrieveChirpsByAuthorDesc :many
-select id, created_at, updated_at, body, user_id from chirps where user_id = $1 order by created_at desc
+select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.publish_at, chirps.visibility, chirps.publish_attempts, chirps.next_publish_attempt_at from chirps join users on users.id = chirps.user_id
+where chirps.user_id = $1 and chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = $2::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = $2::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = $2::uuid))
+  and (chirps.visibility = 'public' or chirps.user_id = $2::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = $2::uuid)))
+order by chirps.created_at desc
+`
+
+type RetrieveChirpsByAuthorDescParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) RetrieveChirpsByAuthorDesc(ctx context.Context, arg RetrieveChirpsByAuthorDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirpsByAuthorDesc, arg.UserID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.NextPublishAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const retrieveChirpsByIds = `-- name: RetrieveChirpsByIds :many
+select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.publish_at, chirps.visibility, chirps.publish_attempts, chirps.next_publish_attempt_at from chirps join users on users.id = chirps.user_id
+where chirps.id = any($1::uuid[]) and chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = $2::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = $2::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = $2::uuid))
+  and (chirps.visibility <> 'followers_only' or chirps.user_id = $2::uuid or chirps.user_id in (select followee_id from follows where follower_id = $2::uuid))
 `
 
-func (q *Queries) RetrieveChirpsByAuthorDesc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, retrieveChirpsByAuthorDesc, userID)
+type RetrieveChirpsByIdsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) RetrieveChirpsByIds(ctx context.Context, arg RetrieveChirpsByIdsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirpsByIds, pq.Array(arg.Ids), arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +412,12 @@ func (q *Queries) RetrieveChirpsByAuthorDesc(ctx context.Context, userID uuid.UU
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.NextPublishAttemptAt,
 		); err != nil {
 			return nil, err
 		}
@@ -159,11 +433,83 @@ func (q *Queries) RetrieveChirpsByAuthorDesc(ctx context.Context, userID uuid.UU
 }
 
 const retrieveChirpsDesc = `-- name: RetrieveChirpsDesc :many
-select id, created_at, updated_at, body, user_id from chirps order by created_at desc
+select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.publish_at, chirps.visibility, chirps.publish_attempts, chirps.next_publish_attempt_at from chirps join users on users.id = chirps.user_id
+where chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = $1::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = $1::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = $1::uuid))
+  and chirps.user_id not in (select muted_id from mutes where muter_id = $1::uuid)
+  and (chirps.visibility = 'public' or chirps.user_id = $1::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = $1::uuid)))
+order by chirps.created_at desc
+`
+
+func (q *Queries) RetrieveChirpsDesc(ctx context.Context, viewerID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveChirpsDesc, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.NextPublishAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const retrieveRechirpByUser = `-- name: RetrieveRechirpByUser :one
+select id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at from chirps where user_id = $1 and quoted_chirp_id = $2 and is_rechirp = true
+`
+
+type RetrieveRechirpByUserParams struct {
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+}
+
+func (q *Queries) RetrieveRechirpByUser(ctx context.Context, arg RetrieveRechirpByUserParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, retrieveRechirpByUser, arg.UserID, arg.QuotedChirpID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.NextPublishAttemptAt,
+	)
+	return i, err
+}
+
+const retrieveScheduledChirpsByAuthor = `-- name: RetrieveScheduledChirpsByAuthor :many
+select id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility, publish_attempts, next_publish_attempt_at from chirps where user_id = $1 and publish_at is not null order by publish_at asc, id asc
 `
 
-func (q *Queries) RetrieveChirpsDesc(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, retrieveChirpsDesc)
+func (q *Queries) RetrieveScheduledChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, retrieveScheduledChirpsByAuthor, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -177,6 +523,12 @@ func (q *Queries) RetrieveChirpsDesc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.NextPublishAttemptAt,
 		); err != nil {
 			return nil, err
 		}
@@ -11,12 +11,213 @@ import (
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID       uuid.UUID
+	ChirpID      uuid.UUID
+	CollectionID uuid.NullUUID
+	CreatedAt    time.Time
+}
+
+type BookmarkCollection struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 type Chirp struct {
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Body                 string
+	UserID               uuid.UUID
+	QuotedChirpID        uuid.NullUUID
+	IsRechirp            bool
+	PublishAt            sql.NullTime
+	Visibility           string
+	PublishAttempts      int32
+	NextPublishAttemptAt sql.NullTime
+}
+
+type ChirpAttachment struct {
+	ID                  uuid.UUID
+	ChirpID             uuid.UUID
+	Position            int32
+	StorageKey          string
+	ContentType         string
+	SizeBytes           int64
+	CreatedAt           time.Time
+	Status              string
+	OriginalKey         sql.NullString
+	Width               int32
+	Height              int32
+	ThumbnailKey        string
+	Blurhash            string
+	Attempts            int32
+	ProcessingStartedAt sql.NullTime
+}
+
+type ChirpFlag struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	RuleID    uuid.NullUUID
+	Term      string
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+	CreatedAt   time.Time
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID
+	TagID     uuid.UUID
+	CreatedAt time.Time
+}
+
+type Conversation struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	LastMessageAt time.Time
+	DirectKey     sql.NullString
+}
+
+type ConversationParticipant struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type Draft struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Body          string
+	QuotedChirpID uuid.NullUUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type EmailDigest struct {
+	UserID      uuid.UUID
+	Frequency   string
+	PeriodStart time.Time
+	CreatedAt   time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type FollowEvent struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	Event      string
+}
+
+type HandleHistory struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Handle    string
+	CreatedAt time.Time
+}
+
+type Message struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+	CreatedAt      time.Time
+}
+
+type ModerationAction struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	ModeratorID   uuid.UUID
+	ReportID      uuid.NullUUID
+	Action        string
+	TargetUserID  uuid.NullUUID
+	TargetChirpID uuid.NullUUID
+	Note          string
+}
+
+type ModerationRule struct {
 	ID        uuid.UUID
+	Term      string
+	Action    string
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type MutedWord struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	Term      string
+	ExpiresAt sql.NullTime
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ActorID   uuid.NullUUID
+	Type      string
+	ChirpID   uuid.NullUUID
+	ReportID  uuid.NullUUID
+	GroupKey  sql.NullString
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type PinnedChirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	Position  int32
+	CreatedAt time.Time
+}
+
+type Poll struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	ClosesAt  time.Time
+	CreatedAt time.Time
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	PollID   uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
+	UserID    uuid.UUID
+	OptionID  uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +229,69 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ReporterID uuid.UUID
+	ChirpID    uuid.NullUUID
+	UserID     uuid.UUID
+	Reason     string
+	Details    string
+	Status     string
+	AssigneeID uuid.NullUUID
+	Outcome    sql.NullString
+	ResolvedBy uuid.NullUUID
+	ResolvedAt sql.NullTime
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
+type TimelineEntry struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type TrendingTag struct {
+	TagID      uuid.UUID
+	Name       string
+	Score      float64
+	ChirpCount int32
+	ComputedAt time.Time
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Email               string
+	HashedPassword      string
+	IsChirpyRed         bool
+	PrecomputedTimeline bool
+	Handle              sql.NullString
+	DisplayName         string
+	Bio                 string
+	AvatarUrl           string
+	IsModerator         bool
+	SuspendedUntil      sql.NullTime
+	SuspensionReason    string
+	ShadowBanned        bool
+	DigestFrequency     string
+	Timezone            string
+}
+
+type UserSetting struct {
+	UserID              uuid.UUID
+	InAppDisabled       []string
+	EmailDisabled       []string
+	DiscoverableByEmail bool
+	MentionsFrom        string
+	MessagesFrom        string
+	DefaultVisibility   string
+	UpdatedAt           time.Time
 }
@@ -70,6 +70,15 @@ func (q *Queries) GetRefreshTokenByToken(ctx context.Context, token string) (Ref
 	return i, err
 }
 
+const revokeRefreshTokensByUser = `-- name: RevokeRefreshTokensByUser :exec
+update refresh_tokens set revoked_at = NOW(), updated_at = NOW() where user_id = $1 and revoked_at is null
+`
+
+func (q *Queries) RevokeRefreshTokensByUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokensByUser, userID)
+	return err
+}
+
 const updateRefreshTokenByToken = `-- name: UpdateRefreshTokenByToken :exec
 update refresh_tokens set token = $1, created_at = $2, updated_at = NOW(), user_id = $3, expires_at = $4, revoked_at = $5 where user_id = $3
 `
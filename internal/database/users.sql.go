@@ -7,22 +7,39 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const createHandleHistory = `-- name: CreateHandleHistory :exec
+insert into handle_history (id, user_id, handle, created_at) values (gen_random_uuid(), $1, $2, NOW())
+`
+
+type CreateHandleHistoryParams struct {
+	UserID uuid.UUID
+	Handle string
+}
+
+func (q *Queries) CreateHandleHistory(ctx context.Context, arg CreateHandleHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, createHandleHistory, arg.UserID, arg.Handle)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
-insert into users (id, created_at, updated_at, email, hashed_password) values (gen_random_uuid(), NOW(), NOW(), $1, $2) returning id, created_at, updated_at, email, hashed_password, is_chirpy_red
+insert into users (id, created_at, updated_at, email, hashed_password, handle) values (gen_random_uuid(), NOW(), NOW(), $1, $2, $3) returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, precomputed_timeline, handle, display_name, bio, avatar_url, is_moderator, suspended_until, suspension_reason, shadow_banned, digest_frequency, timezone
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -31,10 +48,30 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.PrecomputedTimeline,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.ShadowBanned,
+		&i.DigestFrequency,
+		&i.Timezone,
 	)
 	return i, err
 }
 
+const deleteUserById = `-- name: DeleteUserById :exec
+delete from users where id = $1
+`
+
+func (q *Queries) DeleteUserById(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserById, id)
+	return err
+}
+
 const emptyUsersTable = `-- name: EmptyUsersTable :exec
 delete from users
 `
@@ -44,8 +81,24 @@ func (q *Queries) EmptyUsersTable(ctx context.Context) error {
 	return err
 }
 
+const getLatestHandleHistory = `-- name: GetLatestHandleHistory :one
+select id, user_id, handle, created_at from handle_history where lower(handle) = lower($1) order by created_at desc limit 1
+`
+
+func (q *Queries) GetLatestHandleHistory(ctx context.Context, handle string) (HandleHistory, error) {
+	row := q.db.QueryRowContext(ctx, getLatestHandleHistory, handle)
+	var i HandleHistory
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Handle,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-select id, created_at, updated_at, email, hashed_password, is_chirpy_red from users where email = $1
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, precomputed_timeline, handle, display_name, bio, avatar_url, is_moderator, suspended_until, suspension_reason, shadow_banned, digest_frequency, timezone from users where email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -58,12 +111,52 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.PrecomputedTimeline,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.ShadowBanned,
+		&i.DigestFrequency,
+		&i.Timezone,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, precomputed_timeline, handle, display_name, bio, avatar_url, is_moderator, suspended_until, suspension_reason, shadow_banned, digest_frequency, timezone from users where lower(handle) = lower($1)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.PrecomputedTimeline,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.ShadowBanned,
+		&i.DigestFrequency,
+		&i.Timezone,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-select id, created_at, updated_at, email, hashed_password, is_chirpy_red from users where id = $1
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, precomputed_timeline, handle, display_name, bio, avatar_url, is_moderator, suspended_until, suspension_reason, shadow_banned, digest_frequency, timezone from users where id = $1
 `
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
@@ -76,12 +169,68 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.PrecomputedTimeline,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.ShadowBanned,
+		&i.DigestFrequency,
+		&i.Timezone,
 	)
 	return i, err
 }
 
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, precomputed_timeline, handle, display_name, bio, avatar_url, is_moderator, suspended_until, suspension_reason, shadow_banned, digest_frequency, timezone from users where lower(handle) = any($1::text[])
+`
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.PrecomputedTimeline,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.IsModerator,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+			&i.ShadowBanned,
+			&i.DigestFrequency,
+			&i.Timezone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUserByID = `-- name: UpdateUserByID :one
-update users set id = $1, created_at = $2, updated_at = NOW(), email = $3, hashed_password = $4, is_chirpy_red = $5 where id = $1 returning id, created_at, updated_at, email, hashed_password, is_chirpy_red
+update users set id = $1, created_at = $2, updated_at = NOW(), email = $3, hashed_password = $4, is_chirpy_red = $5 where id = $1 returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, precomputed_timeline, handle, display_name, bio, avatar_url, is_moderator, suspended_until, suspension_reason, shadow_banned, digest_frequency, timezone
 `
 
 type UpdateUserByIDParams struct {
@@ -108,6 +257,60 @@ func (q *Queries) UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams)
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.PrecomputedTimeline,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.ShadowBanned,
+		&i.DigestFrequency,
+		&i.Timezone,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+update users set handle = $2, display_name = $3, bio = $4, avatar_url = $5, updated_at = NOW() where id = $1 returning id, created_at, updated_at, email, hashed_password, is_chirpy_red, precomputed_timeline, handle, display_name, bio, avatar_url, is_moderator, suspended_until, suspension_reason, shadow_banned, digest_frequency, timezone
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+	AvatarUrl   string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.PrecomputedTimeline,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.ShadowBanned,
+		&i.DigestFrequency,
+		&i.Timezone,
 	)
 	return i, err
 }
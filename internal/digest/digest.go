@@ -0,0 +1,178 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	Off    = "off"
+	Daily  = "daily"
+	Weekly = "weekly"
+)
+
+var (
+	ErrInvalidFrequency = errors.New("frequency must be off, daily or weekly")
+	ErrInvalidTimezone  = errors.New("timezone must be an IANA time zone like Europe/Paris")
+)
+
+// ParseFrequency checks how often a user asked for digests.
+func ParseFrequency(frequency string) (string, error) {
+	switch frequency {
+	case Off, Daily, Weekly:
+		return frequency, nil
+	}
+	return "", ErrInvalidFrequency
+}
+
+// ParseTimezone checks a timezone sent by a client. "Local" is refused since
+// it's the server's.
+func ParseTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// Period returns the last full period before now in loc that a digest
+// covers: yesterday for daily digests, last week from Monday for weekly ones.
+func Period(frequency string, now time.Time, loc *time.Location) (start, end time.Time) {
+	local := now.In(loc)
+	end = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if frequency == Weekly {
+		end = end.AddDate(0, 0, -((int(end.Weekday()) + 6) % 7))
+		return end.AddDate(0, 0, -7), end
+	}
+	return end.AddDate(0, 0, -1), end
+}
+
+// UnsubscribeToken signs userID so an unsubscribe link can't be forged for
+// someone else.
+func UnsubscribeToken(secret []byte, userID uuid.UUID) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("digest-unsubscribe:" + userID.String()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func ValidUnsubscribeToken(secret []byte, userID uuid.UUID, token string) bool {
+	return hmac.Equal([]byte(token), []byte(UnsubscribeToken(secret, userID)))
+}
+
+// UnsubscribeURL is the link to stop a user's digests. Opening it asks to
+// confirm, POSTing to it unsubscribes, which mail clients do for one-click
+// unsubscribe.
+func UnsubscribeURL(baseURL string, secret []byte, userID uuid.UUID) string {
+	query := url.Values{}
+	query.Set("user_id", userID.String())
+	query.Set("token", UnsubscribeToken(secret, userID))
+	return strings.TrimSuffix(baseURL, "/") + "/api/digest/unsubscribe?" + query.Encode()
+}
+
+// Chirp is a chirp as a digest shows it.
+type Chirp struct {
+	Author string
+	Body   string
+	Shares int64
+}
+
+// Digest is what's in a user's digest for a period.
+type Digest struct {
+	Handle             string
+	Frequency          string
+	PeriodStart        time.Time
+	PeriodEnd          time.Time
+	NewFollowers       []string
+	NewFollowerCount   int64
+	TopChirps          []Chirp
+	UnreadMentions     []Chirp
+	UnreadMentionCount int64
+	UnsubscribeURL     string
+}
+
+// Empty reports whether there's nothing worth sending.
+func (d Digest) Empty() bool {
+	return d.NewFollowerCount == 0 && len(d.TopChirps) == 0 && d.UnreadMentionCount == 0
+}
+
+// Period is the covered period as the user reads it.
+func (d Digest) Period() string {
+	if d.Frequency == Weekly {
+		return "from " + d.PeriodStart.Format("Jan 2") + " to " + d.PeriodEnd.AddDate(0, 0, -1).Format("Jan 2")
+	}
+	return "on " + d.PeriodStart.Format("Monday, Jan 2")
+}
+
+// Subject is the digest email's subject.
+func (d Digest) Subject() string {
+	return "Your " + d.Frequency + " Chirpy digest"
+}
+
+// MoreFollowers is how many new followers weren't listed.
+func (d Digest) MoreFollowers() int64 {
+	return d.NewFollowerCount - int64(len(d.NewFollowers))
+}
+
+// MoreMentions is how many unread mentions weren't listed.
+func (d Digest) MoreMentions() int64 {
+	return d.UnreadMentionCount - int64(len(d.UnreadMentions))
+}
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/digest.html.tmpl"))
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templates, "templates/digest.txt.tmpl"))
+
+	unsubscribeTemplate = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/unsubscribe.html.tmpl"))
+)
+
+// Render turns a digest into an email to to.
+func Render(to string, d Digest) (Message, error) {
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: d.Subject(),
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: map[string]string{
+			// RFC 8058 one-click unsubscribe
+			"List-Unsubscribe":      "<" + d.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
+
+// UnsubscribePage is the page an unsubscribe link opens. Until Done it asks
+// to confirm with a form POSTing to Action, so a link prefetched by a mail
+// scanner doesn't unsubscribe anyone.
+type UnsubscribePage struct {
+	Action string
+	Done   bool
+}
+
+func (p UnsubscribePage) Render(w io.Writer) error {
+	return unsubscribeTemplate.Execute(w, p)
+}
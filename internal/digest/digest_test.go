@@ -0,0 +1,186 @@
+package digest
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ValidatePeriod struct {
+	suite.Suite
+	paris *time.Location
+}
+
+func (s *ValidatePeriod) SetupSuite() {
+	paris, err := time.LoadLocation("Europe/Paris")
+	s.Require().NoError(err)
+	s.paris = paris
+}
+
+func (s *ValidatePeriod) TestDaily() {
+	// 23:30 UTC on Wednesday is already Thursday in Paris
+	now := time.Date(2026, 3, 4, 23, 30, 0, 0, time.UTC)
+	start, end := Period(Daily, now, time.UTC)
+	assert.Equal(s.T(), time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(s.T(), time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), end)
+
+	start, end = Period(Daily, now, s.paris)
+	assert.Equal(s.T(), time.Date(2026, 3, 4, 0, 0, 0, 0, s.paris), start)
+	assert.Equal(s.T(), time.Date(2026, 3, 5, 0, 0, 0, 0, s.paris), end)
+}
+
+func (s *ValidatePeriod) TestDaylightSaving() {
+	// Paris moves to summer time on March 29 2026, that day is 23 hours
+	now := time.Date(2026, 3, 30, 12, 0, 0, 0, s.paris)
+	start, end := Period(Daily, now, s.paris)
+	assert.Equal(s.T(), 23*time.Hour, end.Sub(start))
+}
+
+func (s *ValidatePeriod) TestWeekly() {
+	// Wednesday March 4 2026, the last full week started Monday February 23
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	start, end := Period(Weekly, now, time.UTC)
+	assert.Equal(s.T(), time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(s.T(), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), end)
+
+	// on a Monday the week that just ended is covered
+	now = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	start, _ = Period(Weekly, now, time.UTC)
+	assert.Equal(s.T(), time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC), start)
+}
+
+func (s *ValidatePeriod) TestParse() {
+	for _, frequency := range []string{Off, Daily, Weekly} {
+		parsed, err := ParseFrequency(frequency)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), frequency, parsed)
+	}
+	_, err := ParseFrequency("hourly")
+	assert.ErrorIs(s.T(), err, ErrInvalidFrequency)
+
+	loc, err := ParseTimezone("Europe/Paris")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "Europe/Paris", loc.String())
+	for _, name := range []string{"", "Local", "Mars/Olympus"} {
+		_, err := ParseTimezone(name)
+		assert.ErrorIs(s.T(), err, ErrInvalidTimezone, name)
+	}
+}
+
+func TestValidatePeriod(t *testing.T) {
+	suite.Run(t, new(ValidatePeriod))
+}
+
+type ValidateUnsubscribe struct {
+	suite.Suite
+}
+
+func (s *ValidateUnsubscribe) TestToken() {
+	secret := []byte("secret")
+	userID := uuid.New()
+	token := UnsubscribeToken(secret, userID)
+	assert.True(s.T(), ValidUnsubscribeToken(secret, userID, token))
+	assert.False(s.T(), ValidUnsubscribeToken(secret, uuid.New(), token))
+	assert.False(s.T(), ValidUnsubscribeToken([]byte("other"), userID, token))
+	assert.False(s.T(), ValidUnsubscribeToken(secret, userID, ""))
+}
+
+func (s *ValidateUnsubscribe) TestURL() {
+	userID := uuid.New()
+	link := UnsubscribeURL("https://chirpy.example/", []byte("secret"), userID)
+	assert.True(s.T(), strings.HasPrefix(link, "https://chirpy.example/api/digest/unsubscribe?"))
+	assert.Contains(s.T(), link, "user_id="+userID.String())
+	assert.Contains(s.T(), link, "token="+UnsubscribeToken([]byte("secret"), userID))
+}
+
+func (s *ValidateUnsubscribe) TestPage() {
+	var confirm strings.Builder
+	page := UnsubscribePage{Action: "/api/digest/unsubscribe?user_id=1&token=abc"}
+	assert.NoError(s.T(), page.Render(&confirm))
+	assert.Contains(s.T(), confirm.String(), `<form method="post" action="/api/digest/unsubscribe?user_id=1&amp;token=abc">`)
+	assert.NotContains(s.T(), confirm.String(), "anymore")
+
+	var done strings.Builder
+	page.Done = true
+	assert.NoError(s.T(), page.Render(&done))
+	assert.Contains(s.T(), done.String(), "You won't get Chirpy digest emails anymore.")
+	assert.NotContains(s.T(), done.String(), "<form")
+}
+
+func TestValidateUnsubscribe(t *testing.T) {
+	suite.Run(t, new(ValidateUnsubscribe))
+}
+
+type ValidateRender struct {
+	suite.Suite
+}
+
+func (s *ValidateRender) digest() Digest {
+	return Digest{
+		Handle:           "saul",
+		Frequency:        Daily,
+		PeriodStart:      time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:        time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+		NewFollowers:     []string{"@kim"},
+		NewFollowerCount: 3,
+		TopChirps: []Chirp{
+			{Author: "@mike", Body: "<b>hello</b> & goodbye", Shares: 2},
+		},
+		UnsubscribeURL: "https://chirpy.example/api/digest/unsubscribe?user_id=1&token=abc",
+	}
+}
+
+func (s *ValidateRender) TestRender() {
+	msg, err := Render("saul@example.com", s.digest())
+	s.Require().NoError(err)
+	assert.Equal(s.T(), "saul@example.com", msg.To)
+	assert.Equal(s.T(), "Your daily Chirpy digest", msg.Subject)
+
+	assert.Contains(s.T(), msg.Text, "Hi @saul, here's what happened on Chirpy on Tuesday, Mar 3.")
+	assert.Contains(s.T(), msg.Text, "@kim")
+	assert.Contains(s.T(), msg.Text, "and 2 more")
+	assert.Contains(s.T(), msg.Text, "<b>hello</b> & goodbye (2 shares)")
+	assert.NotContains(s.T(), msg.Text, "Unread mentions")
+	assert.Contains(s.T(), msg.Text, "user_id=1&token=abc")
+
+	assert.Contains(s.T(), msg.HTML, "&lt;b&gt;hello&lt;/b&gt; &amp; goodbye")
+	assert.Contains(s.T(), msg.HTML, `href="https://chirpy.example/api/digest/unsubscribe?user_id=1&amp;token=abc"`)
+
+	assert.Equal(s.T(), "<https://chirpy.example/api/digest/unsubscribe?user_id=1&token=abc>", msg.Headers["List-Unsubscribe"])
+	assert.Equal(s.T(), "List-Unsubscribe=One-Click", msg.Headers["List-Unsubscribe-Post"])
+}
+
+func (s *ValidateRender) TestWeeklyPeriod() {
+	d := s.digest()
+	d.Frequency = Weekly
+	d.PeriodStart = time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC)
+	d.PeriodEnd = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	assert.Equal(s.T(), "from Feb 23 to Mar 1", d.Period())
+}
+
+func (s *ValidateRender) TestEmpty() {
+	assert.False(s.T(), s.digest().Empty())
+	assert.True(s.T(), Digest{Frequency: Daily}.Empty())
+}
+
+func (s *ValidateRender) TestBytes() {
+	msg, err := Render("saul@example.com", s.digest())
+	s.Require().NoError(err)
+	raw, err := msg.Bytes("Chirpy <digest@chirpy.example>", time.Date(2026, 3, 4, 6, 0, 0, 0, time.UTC))
+	s.Require().NoError(err)
+	email := string(raw)
+	assert.Contains(s.T(), email, "From: Chirpy <digest@chirpy.example>\r\n")
+	assert.Contains(s.T(), email, "To: saul@example.com\r\n")
+	assert.Contains(s.T(), email, "Subject: Your daily Chirpy digest\r\n")
+	assert.Contains(s.T(), email, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	assert.Contains(s.T(), email, "Content-Type: multipart/alternative; boundary=")
+	assert.Contains(s.T(), email, "Content-Type: text/plain; charset=utf-8")
+	assert.Contains(s.T(), email, "Content-Type: text/html; charset=utf-8")
+}
+
+func TestValidateRender(t *testing.T) {
+	suite.Run(t, new(ValidateRender))
+}
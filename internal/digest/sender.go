@@ -0,0 +1,176 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"chirpy/internal/database"
+	"chirpy/internal/notifications"
+	"chirpy/internal/settings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultInterval = 15 * time.Minute
+	// MaxItems is how many followers, chirps and mentions a digest lists.
+	MaxItems = 5
+	// subscriberPageSize is how many subscribers are read at a time.
+	subscriberPageSize = 500
+)
+
+// Sender emails digests to the users who asked for them once their period
+// is over in their timezone, leaving out what they turned off for email.
+// Each user's period is claimed in email_digests in the transaction the
+// email is sent from, so a digest is sent once even with several instances
+// running a Sender, and one that failed to send is retried on the next run.
+type Sender struct {
+	DB      *sql.DB
+	Queries *database.Queries
+	Mailer  Mailer
+	// Secret signs unsubscribe links.
+	Secret []byte
+	// BaseURL is where the API is reached from, for the unsubscribe link.
+	BaseURL  string
+	Interval time.Duration
+}
+
+// Send sends every digest due at now.
+func (s Sender) Send(ctx context.Context, now time.Time) error {
+	after := uuid.NullUUID{}
+	for {
+		users, err := s.Queries.RetrieveDigestSubscribers(ctx, database.RetrieveDigestSubscribersParams{
+			AfterID:  after,
+			PageSize: subscriberPageSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := s.sendTo(ctx, user, now); err != nil {
+				log.Printf("error sending digest to %s: %v", user.ID, err)
+			}
+		}
+		if len(users) < subscriberPageSize {
+			return nil
+		}
+		after = uuid.NullUUID{UUID: users[len(users)-1].ID, Valid: true}
+	}
+}
+
+func (s Sender) sendTo(ctx context.Context, user database.User, now time.Time) error {
+	loc, err := ParseTimezone(user.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, end := Period(user.DigestFrequency, now, loc)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := s.Queries.WithTx(tx)
+	claimed, err := qtx.ClaimEmailDigest(ctx, database.ClaimEmailDigestParams{
+		UserID:    user.ID,
+		Frequency: user.DigestFrequency,
+		// the period's date where the user is
+		PeriodStart: time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		return err
+	}
+	if claimed == 0 {
+		return nil
+	}
+
+	userSettings, err := settings.Load(ctx, qtx, user.ID)
+	if err != nil {
+		return err
+	}
+	d := Digest{
+		Handle:         user.Handle.String,
+		Frequency:      user.DigestFrequency,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		UnsubscribeURL: UnsubscribeURL(s.BaseURL, s.Secret, user.ID),
+	}
+	if userSettings.Email(notifications.TypeFollow) {
+		followers, err := qtx.RetrieveDigestNewFollowers(ctx, database.RetrieveDigestNewFollowersParams{
+			UserID:   user.ID,
+			Since:    start.UTC(),
+			Until:    end.UTC(),
+			PageSize: MaxItems,
+		})
+		if err != nil {
+			return err
+		}
+		for _, follower := range followers {
+			d.NewFollowers = append(d.NewFollowers, author(follower.Handle))
+			d.NewFollowerCount = follower.Total
+		}
+	}
+	chirps, err := qtx.RetrieveDigestTopChirps(ctx, database.RetrieveDigestTopChirpsParams{
+		UserID:   user.ID,
+		Since:    start.UTC(),
+		Until:    end.UTC(),
+		PageSize: MaxItems,
+	})
+	if err != nil {
+		return err
+	}
+	for _, chirp := range chirps {
+		d.TopChirps = append(d.TopChirps, Chirp{Author: author(chirp.Handle), Body: chirp.Body, Shares: chirp.Shares})
+	}
+	if userSettings.Email(notifications.TypeMention) {
+		mentions, err := qtx.RetrieveDigestUnreadMentions(ctx, database.RetrieveDigestUnreadMentionsParams{
+			UserID:   user.ID,
+			Until:    end.UTC(),
+			PageSize: MaxItems,
+		})
+		if err != nil {
+			return err
+		}
+		for _, mention := range mentions {
+			d.UnreadMentions = append(d.UnreadMentions, Chirp{Author: author(mention.Handle), Body: mention.Body})
+			d.UnreadMentionCount = mention.Total
+		}
+	}
+
+	// an empty period is still claimed, there's nothing to send for it
+	if !d.Empty() {
+		msg, err := Render(user.Email, d)
+		if err != nil {
+			return err
+		}
+		if err := s.Mailer.Send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func author(handle sql.NullString) string {
+	if !handle.Valid {
+		return "someone"
+	}
+	return "@" + handle.String
+}
+
+// Run sends due digests every Interval until ctx is done.
+func (s Sender) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Send(ctx, time.Now()); err != nil {
+			log.Printf("error sending digests: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
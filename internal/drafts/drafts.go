@@ -0,0 +1,33 @@
+package drafts
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	// MaxPerUser is how many drafts a user can keep at once.
+	MaxPerUser = 100
+	// MaxBodyLength is the longest draft, in characters. Drafts can run
+	// over the length of a chirp while they're being worked on, they're
+	// held to it when published.
+	MaxBodyLength = 1000
+)
+
+var (
+	ErrTooMany     = fmt.Errorf("you can keep at most %d drafts", MaxPerUser)
+	ErrBodyTooLong = fmt.Errorf("draft must be at most %d characters", MaxBodyLength)
+	ErrEmpty       = errors.New("draft needs a body or a quoted chirp")
+)
+
+// Validate checks the contents of a draft before it's saved.
+func Validate(body string, quoting bool) error {
+	if utf8.RuneCountInString(body) > MaxBodyLength {
+		return ErrBodyTooLong
+	}
+	if body == "" && !quoting {
+		return ErrEmpty
+	}
+	return nil
+}
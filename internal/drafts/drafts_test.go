@@ -0,0 +1,24 @@
+package drafts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ValidateDrafts struct {
+	suite.Suite
+}
+
+func (s *ValidateDrafts) TestValidate() {
+	assert.NoError(s.T(), Validate("half a thought", false))
+	assert.NoError(s.T(), Validate("", true))
+	assert.NoError(s.T(), Validate(strings.Repeat("é", MaxBodyLength), false))
+	assert.ErrorIs(s.T(), Validate("", false), ErrEmpty)
+	assert.ErrorIs(s.T(), Validate(strings.Repeat("a", MaxBodyLength+1), false), ErrBodyTooLong)
+}
+
+func TestValidateDrafts(t *testing.T) {
+	suite.Run(t, new(ValidateDrafts))
+}
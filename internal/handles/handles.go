@@ -0,0 +1,81 @@
+package handles
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxLength is the longest handle a user can pick.
+const MaxLength = 15
+
+var (
+	ErrInvalid  = fmt.Errorf("handle must be 1 to %d letters, digits or underscores", MaxLength)
+	ErrReserved = errors.New("handle is reserved")
+)
+
+// reserved handles would be mistaken for routes under /api/users or for
+// someone speaking for Chirpy.
+var reserved = map[string]bool{
+	"about":         true,
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"chirpy":        true,
+	"help":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"mentions":      true,
+	"moderator":     true,
+	"notifications": true,
+	"null":          true,
+	"polka":         true,
+	"root":          true,
+	"settings":      true,
+	"signup":        true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+	"tags":          true,
+	"timeline":      true,
+	"undefined":     true,
+}
+
+// IsHandleByte reports whether b can be part of a handle. Handles are
+// limited to ASCII letters, digits and underscores.
+func IsHandleByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_'
+}
+
+// Valid reports whether handle is well formed. It doesn't check reserved
+// handles, an existing user may still be mentioned by one.
+func Valid(handle string) bool {
+	if len(handle) == 0 || len(handle) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(handle); i++ {
+		if !IsHandleByte(handle[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that a user may pick handle.
+func Validate(handle string) error {
+	if !Valid(handle) {
+		return ErrInvalid
+	}
+	if reserved[Normalize(handle)] {
+		return ErrReserved
+	}
+	return nil
+}
+
+// Normalize returns the form handles are compared in, they are unique
+// regardless of case.
+func Normalize(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
@@ -0,0 +1,40 @@
+package handles
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ValidateHandles struct {
+	suite.Suite
+}
+
+func (s *ValidateHandles) TestValid() {
+	for _, handle := range []string{"a", "alice", "Bob_99", "_", strings.Repeat("x", MaxLength)} {
+		assert.NoError(s.T(), Validate(handle), handle)
+	}
+}
+
+func (s *ValidateHandles) TestInvalid() {
+	for _, handle := range []string{"", "with space", "dash-ed", "@alice", "josé", strings.Repeat("x", MaxLength+1)} {
+		assert.ErrorIs(s.T(), Validate(handle), ErrInvalid, handle)
+	}
+}
+
+func (s *ValidateHandles) TestReserved() {
+	for _, handle := range []string{"me", "Admin", "SETTINGS", "chirpy"} {
+		assert.ErrorIs(s.T(), Validate(handle), ErrReserved, handle)
+		assert.True(s.T(), Valid(handle), handle)
+	}
+}
+
+func (s *ValidateHandles) TestNormalize() {
+	assert.Equal(s.T(), "alice", Normalize("@Alice"))
+	assert.Equal(s.T(), Normalize("BOB_99"), Normalize("bob_99"))
+}
+
+func TestValidateHandles(t *testing.T) {
+	suite.Run(t, new(ValidateHandles))
+}
@@ -0,0 +1,50 @@
+package hashtags
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxLength is the longest hashtag, in runes, that is extracted.
+const MaxLength = 100
+
+// Extract returns the distinct hashtags in body, lower cased and without the
+// leading '#', in the order they first appear. A hashtag has to start the
+// body or follow a character that can't be part of one, so "c#" or
+// "&#39;" aren't hashtags, and it needs at least one letter.
+func Extract(body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' {
+			continue
+		}
+		if i > 0 && (isTagRune(runes[i-1]) || runes[i-1] == '&') {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isTagRune(runes[end]) {
+			end++
+		}
+		name := runes[i+1 : end]
+		i = end - 1
+		tag := Normalize(string(name))
+		if len(name) > MaxLength || !strings.ContainsFunc(tag, unicode.IsLetter) || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Normalize turns a hashtag as typed by a user into the form it's stored
+// and looked up with.
+func Normalize(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_'
+}
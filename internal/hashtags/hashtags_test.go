@@ -0,0 +1,85 @@
+package hashtags
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ValidateExtract struct {
+	suite.Suite
+}
+
+func (s *ValidateExtract) TestExtract() {
+	cases := map[string][]string{
+		"no tags here":                       nil,
+		"#Go is great":                       {"go"},
+		"learning #golang and #Go, #GOLANG!": {"golang", "go"},
+		"tabs\t#one\nnewline #two":           {"one", "two"},
+		"#2024 is not a tag but #y2024 is":   {"y2024"},
+		"c# and foo#bar are not tags":        nil,
+		"html &#39; entity":                  nil,
+		"#café #日本語 #snake_case":             {"café", "日本語", "snake_case"},
+		"lonely # and ##double":              {"double"},
+	}
+	for body, expected := range cases {
+		assert.Equal(s.T(), expected, Extract(body), body)
+	}
+}
+
+func (s *ValidateExtract) TestTooLong() {
+	assert.Nil(s.T(), Extract("#"+strings.Repeat("a", MaxLength+1)))
+	assert.Equal(s.T(), []string{strings.Repeat("a", MaxLength)}, Extract("#"+strings.Repeat("a", MaxLength)))
+}
+
+func TestValidateExtract(t *testing.T) {
+	suite.Run(t, new(ValidateExtract))
+}
+
+type ValidateRank struct {
+	suite.Suite
+	now time.Time
+}
+
+func (s *ValidateRank) SetupSuite() {
+	s.now = time.Date(2024, 10, 28, 12, 0, 0, 0, time.UTC)
+}
+
+func (s *ValidateRank) TestRecentUsesWin() {
+	old, recent := uuid.New(), uuid.New()
+	var uses []Use
+	// three uses a day ago are worth less than two uses right now
+	for i := 0; i < 3; i++ {
+		uses = append(uses, Use{TagID: old, Name: "old", At: s.now.Add(-20 * time.Hour)})
+	}
+	for i := 0; i < 2; i++ {
+		uses = append(uses, Use{TagID: recent, Name: "recent", At: s.now})
+	}
+	trends := Rank(uses, s.now, DefaultHalfLife, DefaultLimit)
+	assert.Len(s.T(), trends, 2)
+	assert.Equal(s.T(), "recent", trends[0].Name)
+	assert.Equal(s.T(), 2, trends[0].Count)
+	assert.InDelta(s.T(), 2.0, trends[0].Score, 1e-9)
+	assert.Equal(s.T(), 3, trends[1].Count)
+}
+
+func (s *ValidateRank) TestHalfLife() {
+	tag := uuid.New()
+	trends := Rank([]Use{{TagID: tag, Name: "tag", At: s.now.Add(-DefaultHalfLife)}}, s.now, DefaultHalfLife, DefaultLimit)
+	assert.InDelta(s.T(), 0.5, trends[0].Score, 1e-9)
+}
+
+func (s *ValidateRank) TestLimit() {
+	var uses []Use
+	for i := 0; i < DefaultLimit+5; i++ {
+		uses = append(uses, Use{TagID: uuid.New(), Name: uuid.NewString(), At: s.now})
+	}
+	assert.Len(s.T(), Rank(uses, s.now, DefaultHalfLife, DefaultLimit), DefaultLimit)
+}
+
+func TestValidateRank(t *testing.T) {
+	suite.Run(t, new(ValidateRank))
+}
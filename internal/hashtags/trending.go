@@ -0,0 +1,131 @@
+package hashtags
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultWindow   = 24 * time.Hour
+	DefaultHalfLife = 2 * time.Hour
+	DefaultInterval = 5 * time.Minute
+	DefaultLimit    = 10
+)
+
+// Use is a single chirp using a tag.
+type Use struct {
+	TagID uuid.UUID
+	Name  string
+	At    time.Time
+}
+
+type Trend struct {
+	TagID uuid.UUID
+	Name  string
+	Score float64
+	Count int
+}
+
+// Rank scores tags by their uses, each use counting for less the older it
+// is: a use halfLife old counts half as much as one made now. It returns at
+// most limit tags, highest score first.
+func Rank(uses []Use, now time.Time, halfLife time.Duration, limit int) []Trend {
+	byTag := make(map[uuid.UUID]*Trend)
+	for _, use := range uses {
+		trend, ok := byTag[use.TagID]
+		if !ok {
+			trend = &Trend{TagID: use.TagID, Name: use.Name}
+			byTag[use.TagID] = trend
+		}
+		age := now.Sub(use.At)
+		if age < 0 {
+			age = 0
+		}
+		trend.Score += math.Exp2(-float64(age) / float64(halfLife))
+		trend.Count++
+	}
+	trends := make([]Trend, 0, len(byTag))
+	for _, trend := range byTag {
+		trends = append(trends, *trend)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Score != trends[j].Score {
+			return trends[i].Score > trends[j].Score
+		}
+		return trends[i].Name < trends[j].Name
+	})
+	if len(trends) > limit {
+		trends = trends[:limit]
+	}
+	return trends
+}
+
+// Refresher periodically recomputes trending_tags from the tag uses inside
+// Window so that GET /api/tags/trending only has to read the result.
+type Refresher struct {
+	DB       *sql.DB
+	Queries  *database.Queries
+	Window   time.Duration
+	HalfLife time.Duration
+	Interval time.Duration
+	Limit    int
+}
+
+func (r Refresher) Refresh(ctx context.Context) error {
+	now := time.Now().UTC()
+	rows, err := r.Queries.RetrieveTagUsesSince(ctx, now.Add(-r.Window))
+	if err != nil {
+		return err
+	}
+	uses := make([]Use, len(rows))
+	for i, row := range rows {
+		uses[i] = Use{TagID: row.TagID, Name: row.Name, At: row.CreatedAt}
+	}
+	trends := Rank(uses, now, r.HalfLife, r.Limit)
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := r.Queries.WithTx(tx)
+	if err := qtx.DeleteTrendingTags(ctx); err != nil {
+		return err
+	}
+	for _, trend := range trends {
+		err := qtx.CreateTrendingTag(ctx, database.CreateTrendingTagParams{
+			TagID:      trend.TagID,
+			Name:       trend.Name,
+			Score:      trend.Score,
+			ChirpCount: int32(trend.Count),
+			ComputedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Run refreshes trending tags every Interval until ctx is done.
+func (r Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Refresh(ctx); err != nil {
+			log.Printf("error refreshing trending tags: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
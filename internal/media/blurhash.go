@@ -0,0 +1,94 @@
+package media
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash encodes img as a BlurHash (https://blurha.sh), a short string
+// clients can render as a blurred placeholder while the image loads.
+// img should already be small, every pixel is visited once per component.
+func Blurhash(img *image.RGBA, xComponents, yComponents int) string {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					basis := normalisation *
+						math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(h))
+					offset := img.PixOffset(x, y)
+					factor[0] += basis * srgbToLinear(img.Pix[offset])
+					factor[1] += basis * srgbToLinear(img.Pix[offset+1])
+					factor[2] += basis * srgbToLinear(img.Pix[offset+2])
+				}
+			}
+			scale := 1 / float64(w*h)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encode83((xComponents-1)+(yComponents-1)*9, 1))
+	dc, ac := factors[0], factors[1:]
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			for _, component := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(component))
+			}
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantised+1) / 166
+		hash.WriteString(encode83(quantised, 1))
+	} else {
+		hash.WriteString(encode83(0, 1))
+	}
+	hash.WriteString(encode83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, factor := range ac {
+		quantise := func(value float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(value/maximumValue, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encode83(quantise(factor[0])*19*19+quantise(factor[1])*19+quantise(factor[2]), 2))
+	}
+	return hash.String()
+}
+
+func encode83(value, length int) string {
+	encoded := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		encoded[i] = base83Chars[value%83]
+		value /= 83
+	}
+	return string(encoded)
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
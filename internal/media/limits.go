@@ -0,0 +1,141 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// MaxPixels is the most pixels an image, or a frame of a GIF, can
+	// declare. It's checked before decoding, since a few KiB of compressed
+	// data can declare enough pixels to exhaust the worker's memory.
+	MaxPixels = 40_000_000
+	// MaxGIFFrames is how many frames a GIF can have.
+	MaxGIFFrames = 500
+	// MaxGIFPixels is the most pixels all the frames of a GIF can declare
+	// together.
+	MaxGIFPixels = 100_000_000
+)
+
+var (
+	ErrTooManyPixels = fmt.Errorf("images can be at most %d megapixels", MaxPixels/1_000_000)
+	ErrTooManyFrames = fmt.Errorf("gifs can have at most %d frames", MaxGIFFrames)
+)
+
+// checkSize reads the dimensions a JPEG or PNG declares, without decoding
+// it, and refuses the ones too large to decode.
+func checkSize(data []byte, contentType string) error {
+	var config image.Config
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		config, err = jpeg.DecodeConfig(bytes.NewReader(data))
+	case "image/png":
+		config, err = png.DecodeConfig(bytes.NewReader(data))
+	default:
+		return ErrNotAnImage
+	}
+	if err != nil {
+		return ErrUndecodable
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return ErrTooManyPixels
+	}
+	return nil
+}
+
+// checkGIFSize walks the blocks of a GIF without decoding any pixels and
+// refuses the ones whose screen or frames are too large to decode, or that
+// have too many frames.
+func checkGIFSize(data []byte) error {
+	screen, frames, err := gifBounds(data)
+	if err != nil {
+		return err
+	}
+	if int64(screen.X)*int64(screen.Y) > MaxPixels {
+		return ErrTooManyPixels
+	}
+	var total int64
+	for _, frame := range frames {
+		pixels := int64(frame.Dx()) * int64(frame.Dy())
+		if pixels > MaxPixels {
+			return ErrTooManyPixels
+		}
+		total += pixels
+	}
+	if total > MaxGIFPixels {
+		return ErrTooManyPixels
+	}
+	return nil
+}
+
+// gifBounds returns the size of a GIF's logical screen and the bounds of
+// each of its frames. It stops as soon as there are more than MaxGIFFrames.
+func gifBounds(data []byte) (image.Point, []image.Rectangle, error) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return image.Point{}, nil, ErrUndecodable
+	}
+	screen := image.Pt(int(binary.LittleEndian.Uint16(data[6:])), int(binary.LittleEndian.Uint16(data[8:])))
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1)
+	}
+	var frames []image.Rectangle
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // extension: introducer, label, data sub-blocks
+			next, err := skipSubBlocks(data, pos+2)
+			if err != nil {
+				return image.Point{}, nil, err
+			}
+			pos = next
+		case 0x2c: // image descriptor, color table, LZW code size, data
+			if pos+10 > len(data) {
+				return image.Point{}, nil, ErrUndecodable
+			}
+			left := int(binary.LittleEndian.Uint16(data[pos+1:]))
+			top := int(binary.LittleEndian.Uint16(data[pos+3:]))
+			width := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			height := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1)
+			}
+			frames = append(frames, image.Rect(left, top, left+width, top+height))
+			if len(frames) > MaxGIFFrames {
+				return image.Point{}, nil, ErrTooManyFrames
+			}
+			next, err := skipSubBlocks(data, pos+1)
+			if err != nil {
+				return image.Point{}, nil, err
+			}
+			pos = next
+		case 0x3b: // trailer
+			return screen, frames, nil
+		default:
+			return image.Point{}, nil, ErrUndecodable
+		}
+	}
+	return image.Point{}, nil, ErrUndecodable
+}
+
+// skipSubBlocks returns the position after the data sub-blocks starting at
+// pos, which end with an empty one.
+func skipSubBlocks(data []byte, pos int) (int, error) {
+	for {
+		if pos >= len(data) {
+			return 0, ErrUndecodable
+		}
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, nil
+		}
+		pos += size
+	}
+}
@@ -0,0 +1,80 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+const (
+	MaxImagesPerChirp = 4
+	MaxImageBytes     = 5 << 20
+	// MaxUploadBytes bounds a whole multipart chirp request, images plus the
+	// body and the multipart framing around them.
+	MaxUploadBytes = MaxImagesPerChirp*MaxImageBytes + 1<<20
+)
+
+var (
+	ErrTooManyImages = fmt.Errorf("a chirp can carry at most %d images", MaxImagesPerChirp)
+	ErrImageTooLarge = fmt.Errorf("images can be at most %d MiB", MaxImageBytes>>20)
+	ErrNotAnImage    = errors.New("only jpeg, png and gif images are supported")
+)
+
+// extensions are the image types accepted for upload, the ones Process can
+// decode and re-encode with the standard library.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is an uploaded image whose type was sniffed from its content, the
+// Content-Type sent by the client isn't trusted.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+}
+
+// Sniff detects the type of an image from its first bytes.
+func Sniff(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := extensions[contentType]
+	if !ok {
+		return Image{}, ErrNotAnImage
+	}
+	return Image{Data: data, ContentType: contentType, Extension: extension}, nil
+}
+
+// ReadUploads reads and sniffs the images of a multipart chirp request.
+func ReadUploads(files []*multipart.FileHeader) ([]Image, error) {
+	if len(files) > MaxImagesPerChirp {
+		return nil, ErrTooManyImages
+	}
+	images := make([]Image, 0, len(files))
+	for _, header := range files {
+		if header.Size > MaxImageBytes {
+			return nil, ErrImageTooLarge
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, MaxImageBytes+1))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > MaxImageBytes {
+			return nil, ErrImageTooLarge
+		}
+		image, err := Sniff(data)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
@@ -0,0 +1,316 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+type ValidateUploads struct {
+	suite.Suite
+	png []byte
+}
+
+func (s *ValidateUploads) SetupSuite() {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	s.png = buf.Bytes()
+}
+
+// upload builds a multipart request carrying files as "images" and returns
+// the parsed file headers.
+func (s *ValidateUploads) upload(files ...[]byte) []*multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, file := range files {
+		// the declared type is a lie on purpose, it must be ignored
+		part, _ := writer.CreateFormFile("images", "upload.jpg")
+		part.Write(file)
+	}
+	writer.Close()
+	req := httptest.NewRequest("POST", "/api/chirps", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	assert.NoError(s.T(), req.ParseMultipartForm(MaxUploadBytes))
+	return req.MultipartForm.File["images"]
+}
+
+func (s *ValidateUploads) TestSniffsContent() {
+	images, err := ReadUploads(s.upload(s.png))
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), images, 1)
+	assert.Equal(s.T(), "image/png", images[0].ContentType)
+	assert.Equal(s.T(), ".png", images[0].Extension)
+}
+
+func (s *ValidateUploads) TestRejectsNonImages() {
+	_, err := ReadUploads(s.upload([]byte("<html><script>alert(1)</script></html>")))
+	assert.ErrorIs(s.T(), err, ErrNotAnImage)
+}
+
+func (s *ValidateUploads) TestRejectsTooMany() {
+	_, err := ReadUploads(s.upload(s.png, s.png, s.png, s.png, s.png))
+	assert.ErrorIs(s.T(), err, ErrTooManyImages)
+}
+
+func (s *ValidateUploads) TestRejectsTooLarge() {
+	large := append(append([]byte{}, s.png...), make([]byte, MaxImageBytes)...)
+	_, err := ReadUploads(s.upload(large))
+	assert.ErrorIs(s.T(), err, ErrImageTooLarge)
+}
+
+func TestValidateUploads(t *testing.T) {
+	suite.Run(t, new(ValidateUploads))
+}
+
+type ValidateProcess struct {
+	suite.Suite
+}
+
+// halves is a w by h image, red on the left half and blue on the right.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+// withExif inserts an EXIF segment with the given orientation and a fake
+// GPS marker right after the start of a JPEG.
+func withExif(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPS 52.3676N 4.9041E"...)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	out := append([]byte{}, data[:2]...)
+	out = append(out, 0xFF, 0xE1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func (s *ValidateProcess) TestStripsExifAndOrients() {
+	var buf bytes.Buffer
+	assert.NoError(s.T(), jpeg.Encode(&buf, halves(40, 20), nil))
+	data := withExif(buf.Bytes(), 6)
+	assert.Equal(s.T(), 6, jpegOrientation(data))
+
+	processed, err := Process(Image{Data: data, ContentType: "image/jpeg"})
+	assert.NoError(s.T(), err)
+	assert.NotContains(s.T(), string(processed.Data), "Exif")
+	assert.NotContains(s.T(), string(processed.Data), "GPS")
+	assert.Equal(s.T(), 20, processed.Width)
+	assert.Equal(s.T(), 40, processed.Height)
+
+	// rotated a quarter turn clockwise, the red left half is now on top
+	decoded, err := jpeg.Decode(bytes.NewReader(processed.Data))
+	assert.NoError(s.T(), err)
+	r, _, b, _ := decoded.At(10, 5).RGBA()
+	assert.Greater(s.T(), r, b)
+	r, _, b, _ = decoded.At(10, 35).RGBA()
+	assert.Greater(s.T(), b, r)
+}
+
+func (s *ValidateProcess) TestOrientations() {
+	// the top left pixel of the source ends up in the given corner
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	corners := map[int]image.Point{1: {0, 0}, 2: {2, 0}, 3: {2, 1}, 4: {0, 1}, 5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2}}
+	for orientation, corner := range corners {
+		dst := orient(src, orientation)
+		assert.Equal(s.T(), uint8(255), dst.RGBAAt(corner.X, corner.Y).R, "orientation %d", orientation)
+	}
+}
+
+func (s *ValidateProcess) TestStripsPNGChunks() {
+	var buf bytes.Buffer
+	assert.NoError(s.T(), png.Encode(&buf, halves(8, 8)))
+	data := buf.Bytes()
+	// a tEXt chunk right after the signature and IHDR
+	text := []byte("tEXtComment\x00taken at home")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	data = append(append(append([]byte{}, data[:33]...), chunk...), data[33:]...)
+
+	processed, err := Process(Image{Data: data, ContentType: "image/png"})
+	assert.NoError(s.T(), err)
+	assert.NotContains(s.T(), string(processed.Data), "taken at home")
+	assert.Equal(s.T(), "image/png", processed.ThumbnailContentType)
+}
+
+func (s *ValidateProcess) TestScalesDown() {
+	var buf bytes.Buffer
+	assert.NoError(s.T(), png.Encode(&buf, halves(3000, 1000)))
+	processed, err := Process(Image{Data: buf.Bytes(), ContentType: "image/png"})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), MaxDimension, processed.Width)
+	assert.Equal(s.T(), 682, processed.Height)
+
+	thumbnail, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), image.Rect(0, 0, ThumbnailSize, ThumbnailSize), thumbnail.Bounds())
+}
+
+func (s *ValidateProcess) TestKeepsGIFFrames() {
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 3; i++ {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, 10, 10), palette))
+		animation.Delay = append(animation.Delay, 10)
+	}
+	var buf bytes.Buffer
+	assert.NoError(s.T(), gif.EncodeAll(&buf, animation))
+
+	processed, err := Process(Image{Data: buf.Bytes(), ContentType: "image/gif"})
+	assert.NoError(s.T(), err)
+	decoded, err := gif.DecodeAll(bytes.NewReader(processed.Data))
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), decoded.Image, 3)
+	assert.Equal(s.T(), 10, processed.Width)
+}
+
+func (s *ValidateProcess) TestScalesDownGIFs() {
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{Config: image.Config{Width: 3000, Height: 1000, ColorModel: palette}}
+	full := image.NewPaletted(image.Rect(0, 0, 3000, 1000), palette)
+	// a later frame only covering the right part of the screen
+	part := image.NewPaletted(image.Rect(1500, 500, 3000, 1000), palette)
+	for i := range part.Pix {
+		part.Pix[i] = 1
+	}
+	animation.Image = []*image.Paletted{full, part}
+	animation.Delay = []int{10, 10}
+	var buf bytes.Buffer
+	assert.NoError(s.T(), gif.EncodeAll(&buf, animation))
+
+	processed, err := Process(Image{Data: buf.Bytes(), ContentType: "image/gif"})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), MaxDimension, processed.Width)
+	assert.Equal(s.T(), 682, processed.Height)
+	decoded, err := gif.DecodeAll(bytes.NewReader(processed.Data))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), MaxDimension, decoded.Config.Width)
+	assert.Len(s.T(), decoded.Image, 2)
+	assert.Equal(s.T(), image.Rect(1024, 341, 2048, 682), decoded.Image[1].Bounds())
+	assert.Equal(s.T(), uint8(1), decoded.Image[1].ColorIndexAt(1500, 500))
+}
+
+// the dimensions are patched without touching the pixel data, the way a
+// decompression bomb is made
+func (s *ValidateProcess) TestRefusesTooManyPixels() {
+	var buf bytes.Buffer
+	assert.NoError(s.T(), png.Encode(&buf, halves(8, 8)))
+	data := append([]byte{}, buf.Bytes()...)
+	// IHDR: length, type, width, height, ..., crc
+	binary.BigEndian.PutUint32(data[16:], 50000)
+	binary.BigEndian.PutUint32(data[20:], 50000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	_, err := Process(Image{Data: data, ContentType: "image/png"})
+	assert.ErrorIs(s.T(), err, ErrTooManyPixels)
+
+	buf.Reset()
+	assert.NoError(s.T(), jpeg.Encode(&buf, halves(8, 8), nil))
+	data = append([]byte{}, buf.Bytes()...)
+	sof := bytes.Index(data, []byte{0xFF, 0xC0})
+	s.Require().NotEqual(-1, sof)
+	binary.BigEndian.PutUint16(data[sof+5:], 50000)
+	binary.BigEndian.PutUint16(data[sof+7:], 50000)
+	_, err = Process(Image{Data: data, ContentType: "image/jpeg"})
+	assert.ErrorIs(s.T(), err, ErrTooManyPixels)
+
+	buf.Reset()
+	animation := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.Black, color.White})},
+		Delay: []int{0},
+	}
+	assert.NoError(s.T(), gif.EncodeAll(&buf, animation))
+	data = append([]byte{}, buf.Bytes()...)
+	binary.LittleEndian.PutUint16(data[6:], 50000)
+	binary.LittleEndian.PutUint16(data[8:], 50000)
+	_, err = Process(Image{Data: data, ContentType: "image/gif"})
+	assert.ErrorIs(s.T(), err, ErrTooManyPixels)
+}
+
+// craftedGIF is a 10 by 10 GIF with a frame of each of the given sizes,
+// with no pixel data.
+func craftedGIF(frames ...image.Point) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, 10)
+	data = binary.LittleEndian.AppendUint16(data, 10)
+	data = append(data, 0x80, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff)
+	for _, frame := range frames {
+		data = append(data, 0x2c, 0, 0, 0, 0)
+		data = binary.LittleEndian.AppendUint16(data, uint16(frame.X))
+		data = binary.LittleEndian.AppendUint16(data, uint16(frame.Y))
+		data = append(data, 0, 2, 0)
+	}
+	return append(data, 0x3b)
+}
+
+func (s *ValidateProcess) TestRefusesLargeGIFFrames() {
+	_, err := Process(Image{Data: craftedGIF(image.Pt(60000, 60000)), ContentType: "image/gif"})
+	assert.ErrorIs(s.T(), err, ErrTooManyPixels)
+
+	frames := make([]image.Point, MaxGIFFrames+1)
+	for i := range frames {
+		frames[i] = image.Pt(1, 1)
+	}
+	_, err = Process(Image{Data: craftedGIF(frames...), ContentType: "image/gif"})
+	assert.ErrorIs(s.T(), err, ErrTooManyFrames)
+
+	// together the frames are too large even though each one is fine
+	frames = make([]image.Point, MaxGIFPixels/(60000*600)+1)
+	for i := range frames {
+		frames[i] = image.Pt(60000, 600)
+	}
+	_, err = Process(Image{Data: craftedGIF(frames...), ContentType: "image/gif"})
+	assert.ErrorIs(s.T(), err, ErrTooManyPixels)
+
+	_, err = Process(Image{Data: craftedGIF(image.Pt(4, 4))[:20], ContentType: "image/gif"})
+	assert.ErrorIs(s.T(), err, ErrUndecodable)
+}
+
+func (s *ValidateProcess) TestUndecodable() {
+	// a valid signature followed by garbage
+	_, err := Process(Image{Data: append([]byte("\x89PNG\r\n\x1a\n"), "garbage"...), ContentType: "image/png"})
+	assert.ErrorIs(s.T(), err, ErrUndecodable)
+}
+
+func (s *ValidateProcess) TestBlurhash() {
+	black := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := 3; i < len(black.Pix); i += 4 {
+		black.Pix[i] = 255
+	}
+	// the reference encoder's hash of a solid black image
+	assert.Equal(s.T(), "L00000fQfQfQfQfQfQfQfQfQfQfQ", Blurhash(black, 4, 3))
+
+	hash := Blurhash(halves(32, 32), 4, 3)
+	assert.Len(s.T(), hash, 28)
+	assert.NotEqual(s.T(), "L00000fQfQfQfQfQfQfQfQfQfQfQ", hash)
+}
+
+func TestValidateProcess(t *testing.T) {
+	suite.Run(t, new(ValidateProcess))
+}
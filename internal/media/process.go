@@ -0,0 +1,136 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// MaxDimension is the longest side a processed image is scaled down to.
+	MaxDimension = 2048
+	// ThumbnailSize is the side of the square thumbnails.
+	ThumbnailSize = 320
+	JPEGQuality   = 85
+	// blurhashSize is the side images are shrunk to before computing their
+	// blurhash, the placeholder has far less detail than that anyway.
+	blurhashSize        = 32
+	blurhashXComponents = 4
+	blurhashYComponents = 3
+)
+
+var ErrUndecodable = errors.New("image could not be decoded")
+
+// Processed is an image ready to be served: re-encoded without any of the
+// metadata of the upload, scaled down to MaxDimension, with a thumbnail and
+// a blurhash placeholder.
+type Processed struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+	Thumbnail   []byte
+	// ThumbnailContentType is jpeg for jpeg images and png for the others,
+	// to keep transparency.
+	ThumbnailContentType string
+	Blurhash             string
+}
+
+// Process decodes an uploaded image and encodes it again. Only pixels
+// survive the round trip, so EXIF data such as GPS coordinates and camera
+// serials, comments and other ancillary chunks are all dropped. JPEG
+// orientation is applied to the pixels before the EXIF data goes away.
+// Images declaring more than MaxPixels are refused before being decoded.
+func Process(image Image) (Processed, error) {
+	switch image.ContentType {
+	case "image/jpeg":
+		return processJPEG(image.Data)
+	case "image/png":
+		return processPNG(image.Data)
+	case "image/gif":
+		return processGIF(image.Data)
+	}
+	return Processed{}, ErrNotAnImage
+}
+
+func processJPEG(data []byte) (Processed, error) {
+	if err := checkSize(data, "image/jpeg"); err != nil {
+		return Processed{}, err
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUndecodable
+	}
+	img := fit(orient(toRGBA(decoded), jpegOrientation(data)), MaxDimension)
+	var out, thumbnail bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return Processed{}, err
+	}
+	if err := jpeg.Encode(&thumbnail, cover(img, ThumbnailSize), &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return Processed{}, err
+	}
+	return processed(img, out.Bytes(), "image/jpeg", thumbnail.Bytes(), "image/jpeg"), nil
+}
+
+func processPNG(data []byte) (Processed, error) {
+	if err := checkSize(data, "image/png"); err != nil {
+		return Processed{}, err
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUndecodable
+	}
+	img := fit(toRGBA(decoded), MaxDimension)
+	var out, thumbnail bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return Processed{}, err
+	}
+	if err := png.Encode(&thumbnail, cover(img, ThumbnailSize)); err != nil {
+		return Processed{}, err
+	}
+	return processed(img, out.Bytes(), "image/png", thumbnail.Bytes(), "image/png"), nil
+}
+
+// processGIF keeps every frame so animations still play, scaled down to
+// MaxDimension with their palettes. The thumbnail and blurhash come from the
+// first frame.
+func processGIF(data []byte) (Processed, error) {
+	if err := checkGIFSize(data); err != nil {
+		return Processed{}, err
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil || len(decoded.Image) == 0 {
+		return Processed{}, ErrUndecodable
+	}
+	fitGIF(decoded, MaxDimension)
+	// the encoder only ever writes the frames, their timing and the loop
+	// count, comments and application extensions don't survive
+	var out, thumbnail bytes.Buffer
+	if err := gif.EncodeAll(&out, decoded); err != nil {
+		return Processed{}, err
+	}
+	first := toRGBA(decoded.Image[0])
+	if err := png.Encode(&thumbnail, cover(first, ThumbnailSize)); err != nil {
+		return Processed{}, err
+	}
+	result := processed(first, out.Bytes(), "image/gif", thumbnail.Bytes(), "image/png")
+	if decoded.Config.Width > 0 && decoded.Config.Height > 0 {
+		result.Width, result.Height = decoded.Config.Width, decoded.Config.Height
+	}
+	return result, nil
+}
+
+func processed(img *image.RGBA, data []byte, contentType string, thumbnail []byte, thumbnailContentType string) Processed {
+	return Processed{
+		Data:                 data,
+		ContentType:          contentType,
+		Width:                img.Bounds().Dx(),
+		Height:               img.Bounds().Dy(),
+		Thumbnail:            thumbnail,
+		ThumbnailContentType: thumbnailContentType,
+		Blurhash:             Blurhash(fit(img, blurhashSize), blurhashXComponents, blurhashYComponents),
+	}
+}
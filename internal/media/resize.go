@@ -0,0 +1,143 @@
+package media
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+)
+
+// toRGBA copies img into an RGBA image whose bounds start at the origin.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// resize scales the part of src inside rect to w by h, averaging all the
+// source pixels that fall into each destination pixel.
+func resize(src *image.RGBA, rect image.Rectangle, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := rect.Dx(), rect.Dy()
+	for y := 0; y < h; y++ {
+		y0 := rect.Min.Y + y*sh/h
+		y1 := rect.Min.Y + (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := rect.Min.X + x*sw/w
+			x1 := rect.Min.X + (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					n++
+					offset += 4
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// fit scales src down so neither side is longer than max, keeping its
+// aspect ratio. Smaller images are returned as they are.
+func fit(src *image.RGBA, max int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= max && h <= max {
+		return src
+	}
+	w, h = fitSize(w, h, max)
+	return resize(src, src.Bounds(), w, h)
+}
+
+// fitSize is the size a w by h image is scaled down to so neither side is
+// longer than max.
+func fitSize(w, h, max int) (int, int) {
+	if w >= h {
+		h = h * max / w
+		w = max
+	} else {
+		w = w * max / h
+		h = max
+	}
+	return maxInt(w, 1), maxInt(h, 1)
+}
+
+// fitGIF scales an animation down so neither side of its logical screen is
+// longer than max. Each frame keeps its palette: pixels are taken from the
+// nearest source pixel rather than averaged, so nothing is requantized.
+func fitGIF(animation *gif.GIF, max int) {
+	w, h := animation.Config.Width, animation.Config.Height
+	if w <= 0 || h <= 0 || (w <= max && h <= max) {
+		return
+	}
+	nw, nh := fitSize(w, h, max)
+	for i, frame := range animation.Image {
+		animation.Image[i] = scalePaletted(frame, w, h, nw, nh)
+	}
+	animation.Config.Width, animation.Config.Height = nw, nh
+}
+
+// scalePaletted scales a frame of a w by h screen to its place on an nw by
+// nh one.
+func scalePaletted(src *image.Paletted, w, h, nw, nh int) *image.Paletted {
+	bounds := src.Bounds()
+	rect := image.Rect(
+		bounds.Min.X*nw/w,
+		bounds.Min.Y*nh/h,
+		(bounds.Max.X*nw+w-1)/w,
+		(bounds.Max.Y*nh+h-1)/h,
+	)
+	dst := image.NewPaletted(rect, src.Palette)
+	if bounds.Empty() {
+		return dst
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		sy := clamp((2*y+1)*h/(2*nh), bounds.Min.Y, bounds.Max.Y-1)
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			sx := clamp((2*x+1)*w/(2*nw), bounds.Min.X, bounds.Max.X-1)
+			dst.Pix[dst.PixOffset(x, y)] = src.Pix[src.PixOffset(sx, sy)]
+		}
+	}
+	return dst
+}
+
+// cover crops the center of src to a square and scales it to size by size.
+func cover(src *image.RGBA, size int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	side := minInt(w, h)
+	crop := image.Rect((w-side)/2, (h-side)/2, (w-side)/2+side, (h-side)/2+side)
+	return resize(src, crop, size, size)
+}
+
+func clamp(v, lo, hi int) int {
+	return maxInt(lo, minInt(v, hi))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
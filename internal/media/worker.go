@@ -0,0 +1,167 @@
+package media
+
+import (
+	"bytes"
+	"chirpy/internal/database"
+	"chirpy/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPollInterval = 30 * time.Second
+	// MaxAttempts is how many times an attachment is claimed before it's
+	// given up on, so an image that crashes the worker can't loop forever.
+	MaxAttempts = 3
+)
+
+// Worker processes attachments that are still marked as processing. The
+// chirp_attachments table is the queue: attachments are claimed with
+// FOR UPDATE SKIP LOCKED so several instances can run workers side by side,
+// and a claim older than five minutes is taken over, which picks up work
+// lost to a crash or a restart.
+type Worker struct {
+	Queries *database.Queries
+	Storage storage.Storage
+	// PollInterval is how often the queue is checked without a Notify.
+	PollInterval time.Duration
+	wake         chan struct{}
+}
+
+func NewWorker(queries *database.Queries, store storage.Storage) *Worker {
+	return &Worker{
+		Queries:      queries,
+		Storage:      store,
+		PollInterval: DefaultPollInterval,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the worker up after new attachments were queued.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes queued attachments until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			claimed, err := w.ProcessNext(ctx)
+			if err != nil {
+				log.Printf("error processing attachment: %v", err)
+			}
+			if !claimed || err != nil {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessNext claims and processes one attachment. It reports whether there
+// was one to claim.
+func (w *Worker) ProcessNext(ctx context.Context) (bool, error) {
+	attachment, err := w.Queries.ClaimPendingAttachment(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, w.process(ctx, attachment)
+}
+
+func (w *Worker) process(ctx context.Context, attachment database.ChirpAttachment) error {
+	if attachment.Attempts > MaxAttempts || !attachment.OriginalKey.Valid {
+		return w.fail(ctx, attachment)
+	}
+	original := attachment.OriginalKey.String
+	reader, err := w.Storage.Get(ctx, original)
+	if errors.Is(err, storage.ErrNotFound) {
+		return w.fail(ctx, attachment)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, MaxImageBytes+1))
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	image, err := Sniff(data)
+	if err != nil || len(data) > MaxImageBytes {
+		return w.fail(ctx, attachment)
+	}
+	processed, err := Process(image)
+	if errors.Is(err, ErrUndecodable) || errors.Is(err, ErrNotAnImage) || errors.Is(err, ErrTooManyPixels) || errors.Is(err, ErrTooManyFrames) {
+		return w.fail(ctx, attachment)
+	}
+	if err != nil {
+		return err
+	}
+
+	key := attachment.StorageKey
+	thumbnailKey := strings.TrimSuffix(key, path.Ext(key)) + "_thumb" + extensions[processed.ThumbnailContentType]
+	if err := w.Storage.Put(ctx, key, bytes.NewReader(processed.Data), processed.ContentType); err != nil {
+		return err
+	}
+	if err := w.Storage.Put(ctx, thumbnailKey, bytes.NewReader(processed.Thumbnail), processed.ThumbnailContentType); err != nil {
+		return err
+	}
+	rows, err := w.Queries.CompleteAttachment(ctx, database.CompleteAttachmentParams{
+		ID:           attachment.ID,
+		ContentType:  processed.ContentType,
+		SizeBytes:    int64(len(processed.Data)),
+		Width:        int32(processed.Width),
+		Height:       int32(processed.Height),
+		ThumbnailKey: thumbnailKey,
+		Blurhash:     processed.Blurhash,
+	})
+	if err != nil {
+		return err
+	}
+	// the chirp was deleted while its image was being processed
+	if rows == 0 {
+		w.delete(ctx, key, thumbnailKey)
+	}
+	if original != key {
+		w.delete(ctx, original)
+	}
+	return nil
+}
+
+// fail gives up on an attachment and removes its upload, which still has
+// all its metadata.
+func (w *Worker) fail(ctx context.Context, attachment database.ChirpAttachment) error {
+	if err := w.Queries.FailAttachment(ctx, attachment.ID); err != nil {
+		return err
+	}
+	if attachment.OriginalKey.Valid {
+		w.delete(ctx, attachment.OriginalKey.String)
+	}
+	return nil
+}
+
+func (w *Worker) delete(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := w.Storage.Delete(ctx, key); err != nil {
+			log.Printf("error deleting %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package mentions
+
+import (
+	"chirpy/internal/handles"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mention is an @handle in a chirp body. Start and End are byte offsets of
+// the whole mention, '@' included, so body[Start:End] is "@handle".
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// Extract returns every @handle in body in the order they appear. An '@'
+// preceded by a letter, digit or underscore is part of something else,
+// like an email address, and handles longer than handles.MaxLength are
+// not mentions at all.
+func Extract(body string) []Mention {
+	var found []Mention
+	for i := 0; i < len(body); i++ {
+		if body[i] != '@' {
+			continue
+		}
+		if i > 0 && precededByWord(body[:i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(body) && handles.IsHandleByte(body[end]) {
+			end++
+		}
+		handle := body[i+1 : end]
+		if handles.Valid(handle) && !followedByWord(body[end:]) {
+			found = append(found, Mention{Handle: handle, Start: i, End: end})
+		}
+		i = end - 1
+	}
+	return found
+}
+
+func precededByWord(before string) bool {
+	r, _ := utf8.DecodeLastRuneInString(before)
+	return isWordRune(r)
+}
+
+// followedByWord catches handles running into something a handle can't
+// contain: "@josé" doesn't mention "jos" and "@bob@example.com" is an
+// address, not a mention of "bob".
+func followedByWord(after string) bool {
+	if after == "" {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(after)
+	return r == '@' || isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,40 @@
+package mentions
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ValidateExtract struct {
+	suite.Suite
+}
+
+func (s *ValidateExtract) TestExtract() {
+	cases := map[string][]Mention{
+		"no mentions":                nil,
+		"@alice hi":                  {{Handle: "alice", Start: 0, End: 6}},
+		"hi @Bob_99, and @carol!":    {{Handle: "Bob_99", Start: 3, End: 10}, {Handle: "carol", Start: 16, End: 22}},
+		"mail bob@example.com":       nil,
+		"@bob@example.com":           nil,
+		"lone @ sign":                nil,
+		"@sixteencharsxxxx too long": nil,
+		"@josé":                      nil,
+		"émile@bob":                  nil,
+		"(@dave)\n@erin":             {{Handle: "dave", Start: 1, End: 6}, {Handle: "erin", Start: 8, End: 13}},
+	}
+	for body, expected := range cases {
+		assert.Equal(s.T(), expected, Extract(body), body)
+	}
+}
+
+func (s *ValidateExtract) TestOffsets() {
+	body := "café @zoë and @frank"
+	found := Extract(body)
+	assert.Len(s.T(), found, 1)
+	assert.Equal(s.T(), "@frank", body[found[0].Start:found[0].End])
+}
+
+func TestValidateExtract(t *testing.T) {
+	suite.Run(t, new(ValidateExtract))
+}
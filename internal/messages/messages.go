@@ -0,0 +1,58 @@
+package messages
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxParticipants is the size of the largest group conversation,
+	// including whoever started it.
+	MaxParticipants = 10
+	// MaxBodyLength is the longest message, in characters.
+	MaxBodyLength = 1000
+)
+
+var (
+	ErrNoParticipants      = errors.New("a conversation needs someone else in it")
+	ErrTooManyParticipants = fmt.Errorf("a conversation can have at most %d participants", MaxParticipants)
+	ErrEmptyBody           = errors.New("message must not be empty")
+	ErrBodyTooLong         = fmt.Errorf("message must be at most %d characters", MaxBodyLength)
+)
+
+// Participants returns who a conversation started by creator with others is
+// between, creator first. Repeated ids and creator inviting themself are
+// ignored.
+func Participants(creator uuid.UUID, others []uuid.UUID) ([]uuid.UUID, error) {
+	participants := []uuid.UUID{creator}
+	seen := map[uuid.UUID]bool{creator: true}
+	for _, id := range others {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		participants = append(participants, id)
+	}
+	if len(participants) < 2 {
+		return nil, ErrNoParticipants
+	}
+	if len(participants) > MaxParticipants {
+		return nil, ErrTooManyParticipants
+	}
+	return participants, nil
+}
+
+// Validate checks the body of a message before it's sent.
+func Validate(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return ErrEmptyBody
+	}
+	if utf8.RuneCountInString(body) > MaxBodyLength {
+		return ErrBodyTooLong
+	}
+	return nil
+}
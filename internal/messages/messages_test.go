@@ -0,0 +1,51 @@
+package messages
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ValidateMessages struct {
+	suite.Suite
+}
+
+func (s *ValidateMessages) TestParticipants() {
+	me, a, b := uuid.New(), uuid.New(), uuid.New()
+
+	participants, err := Participants(me, []uuid.UUID{a})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []uuid.UUID{me, a}, participants)
+
+	participants, err = Participants(me, []uuid.UUID{a, me, b, a})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []uuid.UUID{me, a, b}, participants)
+
+	_, err = Participants(me, nil)
+	assert.ErrorIs(s.T(), err, ErrNoParticipants)
+	_, err = Participants(me, []uuid.UUID{me})
+	assert.ErrorIs(s.T(), err, ErrNoParticipants)
+
+	others := make([]uuid.UUID, MaxParticipants)
+	for i := range others {
+		others[i] = uuid.New()
+	}
+	_, err = Participants(me, others)
+	assert.ErrorIs(s.T(), err, ErrTooManyParticipants)
+	_, err = Participants(me, others[1:])
+	assert.NoError(s.T(), err)
+}
+
+func (s *ValidateMessages) TestValidate() {
+	assert.NoError(s.T(), Validate("hi"))
+	assert.NoError(s.T(), Validate(strings.Repeat("é", MaxBodyLength)))
+	assert.ErrorIs(s.T(), Validate(""), ErrEmptyBody)
+	assert.ErrorIs(s.T(), Validate(" \n\t"), ErrEmptyBody)
+	assert.ErrorIs(s.T(), Validate(strings.Repeat("a", MaxBodyLength+1)), ErrBodyTooLong)
+}
+
+func TestValidateMessages(t *testing.T) {
+	suite.Run(t, new(ValidateMessages))
+}
@@ -0,0 +1,227 @@
+package moderation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type Action string
+
+const (
+	// ActionMask replaces the matched words with Mask.
+	ActionMask Action = "mask"
+	// ActionReject refuses the chirp.
+	ActionReject Action = "reject"
+	// ActionFlag accepts the chirp as it is and records it for moderators
+	// to review.
+	ActionFlag Action = "flag"
+)
+
+const Mask = "****"
+
+var (
+	ErrInvalidAction = errors.New("action must be one of mask, reject or flag")
+	ErrEmptyTerm     = errors.New("term must contain at least one word")
+)
+
+func (a Action) Valid() bool {
+	return a == ActionMask || a == ActionReject || a == ActionFlag
+}
+
+// Rule matches a word or a phrase of several words. A term ending in '*'
+// also matches words starting with it, "kerfuffle*" matches "kerfuffles".
+type Rule struct {
+	ID     uuid.UUID
+	Term   string
+	Action Action
+}
+
+// Validate checks that a rule has a known action and a term with words in it.
+func (rule Rule) Validate() error {
+	if !rule.Action.Valid() {
+		return ErrInvalidAction
+	}
+	if len(tokenize(strings.TrimSuffix(rule.Term, "*"))) == 0 {
+		return ErrEmptyTerm
+	}
+	return nil
+}
+
+// Match is a rule matching part of a body, Start and End are byte offsets
+// into the body that was checked.
+type Match struct {
+	Rule  Rule
+	Start int
+	End   int
+}
+
+type Result struct {
+	// Body is the checked body with the matches of mask rules replaced.
+	Body    string
+	Matches []Match
+}
+
+func (result Result) Rejected() bool {
+	return result.has(ActionReject)
+}
+
+func (result Result) Flagged() bool {
+	return result.has(ActionFlag)
+}
+
+func (result Result) has(action Action) bool {
+	for _, match := range result.Matches {
+		if match.Rule.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+type compiledRule struct {
+	rule   Rule
+	words  [][]run
+	prefix bool
+}
+
+// Filter checks chirps against a set of rules. Rules can be replaced while
+// chirps are being checked.
+type Filter struct {
+	mu    sync.RWMutex
+	rules []compiledRule
+}
+
+func NewFilter(rules []Rule) *Filter {
+	filter := &Filter{}
+	filter.SetRules(rules)
+	return filter
+}
+
+// SetRules replaces the rules of the filter, invalid rules are skipped.
+func (f *Filter) SetRules(rules []Rule) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Validate() != nil {
+			continue
+		}
+		term := strings.TrimSuffix(rule.Term, "*")
+		tokens := tokenize(term)
+		words := make([][]run, len(tokens))
+		for i, t := range tokens {
+			words[i] = t.Runs
+		}
+		compiled = append(compiled, compiledRule{rule: rule, words: words, prefix: term != rule.Term})
+	}
+	f.mu.Lock()
+	f.rules = compiled
+	f.mu.Unlock()
+}
+
+// Check matches body against the rules. Rules match whole words only, so
+// a rule for "bert" leaves "sharbert" alone, and a phrase matches its words
+// in order whatever separates them.
+func (f *Filter) Check(body string) Result {
+	f.mu.RLock()
+	rules := f.rules
+	f.mu.RUnlock()
+
+	tokens := tokenize(body)
+	spelled := spelledOut(tokens, body)
+	var matches []Match
+	for _, rule := range rules {
+		for i := range tokens {
+			if rule.matchesAt(tokens, i) {
+				last := tokens[i+len(rule.words)-1]
+				matches = append(matches, Match{Rule: rule.rule, Start: tokens[i].Start, End: last.End})
+			}
+		}
+		if len(rule.words) != 1 {
+			continue
+		}
+		for i := range spelled {
+			if rule.matchesAt(spelled, i) {
+				matches = append(matches, Match{Rule: rule.rule, Start: spelled[i].Start, End: spelled[i].End})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	return Result{Body: mask(body, matches), Matches: matches}
+}
+
+func (rule compiledRule) matchesAt(tokens []token, i int) bool {
+	if i+len(rule.words) > len(tokens) {
+		return false
+	}
+	for j, term := range rule.words {
+		prefix := rule.prefix && j == len(rule.words)-1
+		if !matchWord(tokens[i+j].Runs, term, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// mask replaces the matches of mask rules, overlapping matches are masked
+// once.
+func mask(body string, matches []Match) string {
+	var masked strings.Builder
+	end := 0
+	for _, match := range matches {
+		if match.Rule.Action != ActionMask || match.End <= end {
+			continue
+		}
+		if match.Start >= end {
+			masked.WriteString(body[end:match.Start])
+			masked.WriteString(Mask)
+		}
+		end = match.End
+	}
+	masked.WriteString(body[end:])
+	return masked.String()
+}
+
+// ParseRules reads rules from r, one per line as an action followed by the
+// term. Blank lines and lines starting with '#' are skipped:
+//
+//	# action term
+//	mask kerfuffle
+//	reject some banned phrase
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		action, term, _ := strings.Cut(text, " ")
+		rule := Rule{Term: strings.TrimSpace(term), Action: Action(action)}
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// LoadFile reads rules from a file in the format of ParseRules.
+func LoadFile(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseRules(file)
+}
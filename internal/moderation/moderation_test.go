@@ -0,0 +1,161 @@
+package moderation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ValidateFilter struct {
+	suite.Suite
+	filter *Filter
+}
+
+func (s *ValidateFilter) SetupTest() {
+	s.filter = NewFilter([]Rule{
+		{Term: "kerfuffle", Action: ActionMask},
+		{Term: "sharbert", Action: ActionMask},
+		{Term: "fornax", Action: ActionMask},
+		{Term: "buy followers", Action: ActionReject},
+		{Term: "scam*", Action: ActionFlag},
+	})
+}
+
+// TestEvasions is the corpus of ways people spell banned words, every one of
+// them has to be masked.
+func (s *ValidateFilter) TestEvasions() {
+	cases := map[string]string{
+		"what a kerfuffle":             "what a ****",
+		"Kerfuffle!":                   "****!",
+		"KERFUFFLE, again":             "****, again",
+		"line one\nkerfuffle\nthree":   "line one\n****\nthree",
+		"tab\tsharbert\tseparated":     "tab\t****\tseparated",
+		"(fornax)":                     "(****)",
+		"\"sharbert\".":                "\"****\".",
+		"kérfüffle":                    "****",
+		"ｋｅｒｆｕｆｆｌｅ":                    "****",
+		"ker​fuffle":                   "****",
+		"kеrfuffle":                    "****", // cyrillic е
+		"k3rfuffl3":                    "****",
+		"kerfuff1e":                    "****",
+		"sh@rbert":                     "****",
+		"$harbert":                     "****",
+		"f0rnax":                       "****",
+		"kerfuuuuffffle":               "****",
+		"k e r f u f f l e":            "****",
+		"f.o.r.n.a.x is here":          "**** is here",
+		"s-h-a-r-b-e-r-t":              "****",
+		"#kerfuffle and @fornax":       "#**** and @****",
+		"a kerfuffle and a kerfuffle.": "a **** and a ****.",
+	}
+	for body, expected := range cases {
+		result := s.filter.Check(body)
+		assert.Equal(s.T(), expected, result.Body, body)
+		assert.False(s.T(), result.Rejected(), body)
+	}
+}
+
+// TestNoFalsePositives checks words that only contain a banned word, or
+// look like an evasion, are left alone.
+func (s *ValidateFilter) TestNoFalsePositives() {
+	for _, body := range []string{
+		"kerfuffles are plural",
+		"fornaxes",
+		"mysharbert",
+		"forn ax",
+		"f o r n a x e s",
+		"k e r f\nu f f l e",
+		"sharbert@example.com is fine too",
+	} {
+		result := s.filter.Check(body)
+		assert.Equal(s.T(), body, result.Body, body)
+		assert.Empty(s.T(), result.Matches, body)
+	}
+}
+
+// TestRealWords checks words that share letters with a short banned word,
+// but not its spelling, are left alone.
+func (s *ValidateFilter) TestRealWords() {
+	s.filter.SetRules([]Rule{
+		{Term: "ass", Action: ActionMask},
+		{Term: "hell", Action: ActionReject},
+	})
+	for _, body := range []string{
+		"as I said",
+		"a s I said",
+		"heil",
+		"hel",
+		"he11",
+		"hello there",
+		"shell",
+		"mile 1 of 11",
+	} {
+		result := s.filter.Check(body)
+		assert.Equal(s.T(), body, result.Body, body)
+		assert.Empty(s.T(), result.Matches, body)
+	}
+
+	assert.True(s.T(), s.filter.Check("what the h3ll").Rejected())
+	assert.True(s.T(), s.filter.Check("what the hellll").Rejected())
+	assert.Equal(s.T(), "you ****", s.filter.Check("you as5").Body)
+}
+
+func (s *ValidateFilter) TestReject() {
+	result := s.filter.Check("Buy   FOLLOWERS now")
+	assert.True(s.T(), result.Rejected())
+	assert.Len(s.T(), result.Matches, 1)
+	assert.Equal(s.T(), 0, result.Matches[0].Start)
+	assert.Equal(s.T(), len("Buy   FOLLOWERS"), result.Matches[0].End)
+
+	assert.False(s.T(), s.filter.Check("buy some followers").Rejected())
+}
+
+func (s *ValidateFilter) TestFlagPrefix() {
+	result := s.filter.Check("total scammers, not a kerfuffle")
+	assert.True(s.T(), result.Flagged())
+	assert.False(s.T(), result.Rejected())
+	// flagged words stay as they are
+	assert.Equal(s.T(), "total scammers, not a ****", result.Body)
+}
+
+func (s *ValidateFilter) TestSetRules() {
+	s.filter.SetRules([]Rule{{Term: "chirp", Action: ActionMask}})
+	assert.Equal(s.T(), "kerfuffle ****", s.filter.Check("kerfuffle chirp").Body)
+}
+
+func TestValidateFilter(t *testing.T) {
+	suite.Run(t, new(ValidateFilter))
+}
+
+type ValidateRules struct {
+	suite.Suite
+}
+
+func (s *ValidateRules) TestParseRules() {
+	rules, err := ParseRules(strings.NewReader("# action term\nmask kerfuffle\n\nreject buy followers\nflag scam*\n"))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []Rule{
+		{Term: "kerfuffle", Action: ActionMask},
+		{Term: "buy followers", Action: ActionReject},
+		{Term: "scam*", Action: ActionFlag},
+	}, rules)
+}
+
+func (s *ValidateRules) TestParseRulesErrors() {
+	_, err := ParseRules(strings.NewReader("mask kerfuffle\nban fornax\n"))
+	assert.ErrorIs(s.T(), err, ErrInvalidAction)
+	assert.ErrorContains(s.T(), err, "line 2")
+
+	_, err = ParseRules(strings.NewReader("mask !!!\n"))
+	assert.ErrorIs(s.T(), err, ErrEmptyTerm)
+}
+
+func (s *ValidateRules) TestFold() {
+	assert.Equal(s.T(), "kerfuuuff1e", Fold("Kérfuuuff1e"))
+	assert.Equal(s.T(), "kerfuffle", Fold("ｋｅｒｆｕｆｆｌｅ"))
+}
+
+func TestValidateRules(t *testing.T) {
+	suite.Run(t, new(ValidateRules))
+}
@@ -0,0 +1,210 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// lookalikes maps letters from other scripts that render like latin ones,
+// used to spell banned words past a plain comparison.
+var lookalikes = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'і': 'i', 'ј': 'j', 'к': 'k', 'м': 'm',
+	'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// leet maps digits and symbols commonly swapped in for letters. '1', '|'
+// and '!' stand for either 'i' or 'l' and map to ambiguous.
+var leet = map[rune]rune{
+	'0': 'o', '1': ambiguous, '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': ambiguous, '|': ambiguous, '+': 't',
+}
+
+// ambiguous is the letter of a run of '1', '|' or '!', which matches either
+// 'i' or 'l'.
+const ambiguous = '1'
+
+// isLeetSymbol reports whether r is a symbol that can stand for a letter
+// inside a word.
+func isLeetSymbol(r rune) bool {
+	switch r {
+	case '@', '$', '!', '|', '+':
+		return true
+	}
+	return false
+}
+
+// isWordRune reports whether r is part of a word. Format characters such as
+// zero width spaces count so they can't split a word in two.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf)
+}
+
+// Fold reduces a word to the form it's compared in: compatibility
+// decomposed (full width letters, ligatures), without accents or invisible
+// characters, lower cased and with lookalike letters replaced. Leetspeak
+// and repeated letters are left for the comparison, "Kérfuuuff1e" folds to
+// "kerfuuuff1e".
+func Fold(word string) string {
+	var folded strings.Builder
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if replacement, ok := lookalikes[r]; ok {
+			r = replacement
+		}
+		folded.WriteRune(r)
+	}
+	return folded.String()
+}
+
+// run is a letter repeated n times in a word, subs of which are leetspeak
+// standing in for it.
+type run struct {
+	letter rune
+	n      int
+	subs   int
+}
+
+// runsOf splits a folded word into runs of the same letter.
+func runsOf(key string) []run {
+	var runs []run
+	for _, r := range key {
+		letter, sub := r, false
+		if replacement, ok := leet[r]; ok {
+			letter, sub = replacement, true
+		}
+		if last := len(runs) - 1; last >= 0 && runs[last].letter == letter {
+			runs[last].n++
+		} else {
+			runs = append(runs, run{letter: letter, n: 1})
+		}
+		if sub {
+			runs[len(runs)-1].subs++
+		}
+	}
+	return runs
+}
+
+// matchWord reports whether word spells term, or starts with it for a
+// prefix. Letters of term may be repeated in word but not dropped, so
+// "kerfuuuffle" spells "kerfuffle" while "hel" doesn't spell "hell" and "as"
+// doesn't spell "ass". Leetspeak must make up less than half of word, "he11"
+// reads as a code rather than as "hell".
+func matchWord(word, term []run, prefix bool) bool {
+	if len(word) < len(term) || !prefix && len(word) != len(term) {
+		return false
+	}
+	length, subs := 0, 0
+	for _, w := range word {
+		length += w.n
+	}
+	for i, t := range term {
+		w := word[i]
+		if w.n < t.n || !sameLetter(w.letter, t.letter) {
+			return false
+		}
+		// leetspeak spelled out in the term itself, as in "h1n1", is literal
+		if t.subs == 0 {
+			subs += w.subs
+		}
+	}
+	return 2*subs < length
+}
+
+func sameLetter(w, t rune) bool {
+	return w == t || w == ambiguous && (t == 'i' || t == 'l')
+}
+
+// token is a word of a body, Start and End are byte offsets into it.
+type token struct {
+	Key   string
+	Runs  []run
+	Start int
+	End   int
+}
+
+// tokenize splits body into words on whitespace and punctuation. Leetspeak
+// symbols stay part of a word when a word character follows them, so
+// "sh@rbert" is one word but the "!" in "kerfuffle!" isn't; '$' may also
+// start a word. '@' and '#' starting a word are mention and hashtag
+// markers, not letters.
+func tokenize(body string) []token {
+	runes := []rune(body)
+	offsets := make([]int, len(runes)+1)
+	offset := 0
+	for i, r := range runes {
+		offsets[i] = offset
+		offset += len(string(r))
+	}
+	offsets[len(runes)] = offset
+
+	var tokens []token
+	start := -1
+	for i, r := range runes {
+		inWord := isWordRune(r)
+		if !inWord && isLeetSymbol(r) && i+1 < len(runes) && isWordRune(runes[i+1]) {
+			inWord = start >= 0 || r == '$'
+		}
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			tokens = appendToken(tokens, body, offsets[start], offsets[i])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, body, offsets[start], offsets[len(runes)])
+	}
+	return tokens
+}
+
+func appendToken(tokens []token, body string, start, end int) []token {
+	key := Fold(body[start:end])
+	if key == "" {
+		return tokens
+	}
+	return append(tokens, token{Key: key, Runs: runsOf(key), Start: start, End: end})
+}
+
+// spelledOut joins runs of single letter words separated by one space or
+// punctuation character, such as "k e r f u f f l e" or "f.o.r.n.a.x",
+// into the word they spell.
+func spelledOut(tokens []token, body string) []token {
+	var words []token
+	for i := 0; i < len(tokens); {
+		j := i
+		for j+1 < len(tokens) && isLetter(tokens[j], body) && isLetter(tokens[j+1], body) && isSpacer(body[tokens[j].End:tokens[j+1].Start]) {
+			j++
+		}
+		if j > i {
+			word := token{Start: tokens[i].Start, End: tokens[j].End}
+			var key strings.Builder
+			for _, t := range tokens[i : j+1] {
+				key.WriteString(t.Key)
+			}
+			word.Key = key.String()
+			word.Runs = runsOf(word.Key)
+			words = append(words, word)
+		}
+		i = j + 1
+	}
+	return words
+}
+
+func isLetter(t token, body string) bool {
+	return len([]rune(t.Key)) == 1 && len([]rune(body[t.Start:t.End])) == 1
+}
+
+func isSpacer(gap string) bool {
+	runes := []rune(gap)
+	return len(runes) == 1 && runes[0] != '\n'
+}
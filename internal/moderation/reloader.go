@@ -0,0 +1,48 @@
+package moderation
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"log"
+	"time"
+)
+
+const DefaultReloadInterval = time.Minute
+
+// Reloader keeps a Filter in sync with the moderation_rules table. Changes
+// made through this instance are applied right away with Reload, Run picks
+// up changes made through other instances.
+type Reloader struct {
+	Filter   *Filter
+	Queries  *database.Queries
+	Interval time.Duration
+}
+
+func (r Reloader) Reload(ctx context.Context) error {
+	rows, err := r.Queries.GetModerationRules(ctx)
+	if err != nil {
+		return err
+	}
+	rules := make([]Rule, len(rows))
+	for i, row := range rows {
+		rules[i] = Rule{ID: row.ID, Term: row.Term, Action: Action(row.Action)}
+	}
+	r.Filter.SetRules(rules)
+	return nil
+}
+
+// Run reloads the rules every Interval until ctx is done.
+func (r Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Reload(ctx); err != nil {
+			log.Printf("error reloading moderation rules: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
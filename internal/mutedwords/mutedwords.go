@@ -0,0 +1,89 @@
+package mutedwords
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"chirpy/internal/hashtags"
+	"chirpy/internal/moderation"
+)
+
+const (
+	// MaxTermLength is the longest term, in runes, a user can mute.
+	MaxTermLength = 100
+	// MaxPerUser is how many terms a user can have muted at once.
+	MaxPerUser = 200
+)
+
+var (
+	ErrEmptyTerm   = errors.New("term must contain a word or be a hashtag")
+	ErrTermTooLong = fmt.Errorf("term must be at most %d characters", MaxTermLength)
+)
+
+// Validate checks that term can be muted. A term starting with '#' has to
+// be a single hashtag, anything else is matched like a moderation rule.
+func Validate(term string) error {
+	if utf8.RuneCountInString(term) > MaxTermLength {
+		return ErrTermTooLong
+	}
+	if strings.HasPrefix(term, "#") {
+		tags := hashtags.Extract(term)
+		if len(tags) != 1 || tags[0] != hashtags.Normalize(term) {
+			return ErrEmptyTerm
+		}
+		return nil
+	}
+	if err := (moderation.Rule{Term: term, Action: moderation.ActionFlag}).Validate(); err != nil {
+		return ErrEmptyTerm
+	}
+	return nil
+}
+
+// Matcher tells whether a chirp contains any of a user's muted terms.
+// Hashtag terms only match the hashtag, "#go" hides "#go" but not "go".
+// Other terms match words and phrases with the evasions the moderation
+// filter sees through, "spoiler*" also hides "spoilers".
+type Matcher struct {
+	tags  map[string]bool
+	words *moderation.Filter
+}
+
+// NewMatcher compiles terms once so that a whole page of chirps can be
+// checked against them. Invalid terms are skipped.
+func NewMatcher(terms []string) *Matcher {
+	matcher := &Matcher{tags: make(map[string]bool)}
+	var rules []moderation.Rule
+	for _, term := range terms {
+		if Validate(term) != nil {
+			continue
+		}
+		if strings.HasPrefix(term, "#") {
+			matcher.tags[hashtags.Normalize(term)] = true
+			continue
+		}
+		rules = append(rules, moderation.Rule{Term: term, Action: moderation.ActionFlag})
+	}
+	if len(rules) > 0 {
+		matcher.words = moderation.NewFilter(rules)
+	}
+	return matcher
+}
+
+// Empty reports whether the matcher can't match anything.
+func (m *Matcher) Empty() bool {
+	return len(m.tags) == 0 && m.words == nil
+}
+
+// Matches reports whether body contains one of the muted terms.
+func (m *Matcher) Matches(body string) bool {
+	if len(m.tags) > 0 {
+		for _, tag := range hashtags.Extract(body) {
+			if m.tags[tag] {
+				return true
+			}
+		}
+	}
+	return m.words != nil && len(m.words.Check(body).Matches) > 0
+}
@@ -0,0 +1,55 @@
+package mutedwords
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type ValidateMatcher struct {
+	suite.Suite
+}
+
+func (s *ValidateMatcher) TestValidate() {
+	assert.NoError(s.T(), Validate("spoilers"))
+	assert.NoError(s.T(), Validate("season finale"))
+	assert.NoError(s.T(), Validate("#GoLang"))
+	assert.NoError(s.T(), Validate("spoil*"))
+	assert.ErrorIs(s.T(), Validate(""), ErrEmptyTerm)
+	assert.ErrorIs(s.T(), Validate("!!!"), ErrEmptyTerm)
+	assert.ErrorIs(s.T(), Validate("#"), ErrEmptyTerm)
+	assert.ErrorIs(s.T(), Validate("#one #two"), ErrEmptyTerm)
+	assert.ErrorIs(s.T(), Validate(strings.Repeat("a", MaxTermLength+1)), ErrTermTooLong)
+}
+
+func (s *ValidateMatcher) TestWords() {
+	matcher := NewMatcher([]string{"spoiler*", "season finale"})
+	assert.True(s.T(), matcher.Matches("no SPOILERS please"))
+	assert.True(s.T(), matcher.Matches("that season... finale!"))
+	assert.True(s.T(), matcher.Matches("s p o i l e r"))
+	assert.False(s.T(), matcher.Matches("the finale of the season"))
+	assert.False(s.T(), matcher.Matches("nothing to see"))
+
+	matcher = NewMatcher([]string{"hell"})
+	assert.True(s.T(), matcher.Matches("what the h3ll"))
+	assert.False(s.T(), matcher.Matches("heil, hel or he11"))
+}
+
+func (s *ValidateMatcher) TestHashtags() {
+	matcher := NewMatcher([]string{"#GoLang"})
+	assert.True(s.T(), matcher.Matches("learning #golang today"))
+	assert.False(s.T(), matcher.Matches("learning golang today"))
+	assert.False(s.T(), matcher.Matches("learning #golangci today"))
+}
+
+func (s *ValidateMatcher) TestEmpty() {
+	assert.True(s.T(), NewMatcher(nil).Empty())
+	assert.True(s.T(), NewMatcher([]string{"", "#"}).Empty())
+	assert.False(s.T(), NewMatcher(nil).Matches("anything"))
+	assert.False(s.T(), NewMatcher([]string{"#go"}).Empty())
+}
+
+func TestValidateMatcher(t *testing.T) {
+	suite.Run(t, new(ValidateMatcher))
+}
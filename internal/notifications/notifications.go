@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxActors is how many of the users behind a group of notifications are
+// listed with it, the most recent first.
+const MaxActors = 3
+
+// Types of notifications. ActorID is the user who caused one, ChirpID the
+// chirp it's about when there is one.
+const (
+	TypeFollow         = "follow"
+	TypeMention        = "mention"
+	TypeQuote          = "quote"
+	TypeRechirp        = "rechirp"
+	TypeChirpyRed      = "chirpy_red"
+	TypeReportResolved = "report_resolved"
+)
+
+// Grouped reports whether notifications of a type are shown together, as
+// in "3 people rechirped your chirp", rather than one by one.
+func Grouped(notificationType string) bool {
+	return notificationType == TypeFollow || notificationType == TypeRechirp
+}
+
+// GroupKey is what notifications shown together share: the type, the chirp
+// and the UTC day they happened on. Ungrouped types have none.
+func GroupKey(notificationType string, chirpID uuid.NullUUID, at time.Time) string {
+	if !Grouped(notificationType) {
+		return ""
+	}
+	day := at.UTC().Format(time.DateOnly)
+	if chirpID.Valid {
+		return fmt.Sprintf("%s:%s:%s", notificationType, chirpID.UUID, day)
+	}
+	return fmt.Sprintf("%s:%s", notificationType, day)
+}
+
+// Summary describes a group of count notifications, latest is the handle of
+// whoever caused the most recent one.
+func Summary(notificationType, latest string, count int64) string {
+	who := "someone"
+	if latest != "" {
+		who = "@" + latest
+	}
+	switch {
+	case count == 2:
+		who += " and 1 other"
+	case count > 2:
+		who += fmt.Sprintf(" and %d others", count-1)
+	}
+	switch notificationType {
+	case TypeFollow:
+		return who + " followed you"
+	case TypeMention:
+		return who + " mentioned you"
+	case TypeQuote:
+		return who + " quoted your chirp"
+	case TypeRechirp:
+		return who + " rechirped your chirp"
+	case TypeChirpyRed:
+		return "You're now a Chirpy Red member"
+	case TypeReportResolved:
+		return "A report you made was resolved"
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package notifications
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ValidateNotifications struct {
+	suite.Suite
+}
+
+func (s *ValidateNotifications) TestGroupKey() {
+	chirpID := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	morning := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	evening := time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)
+	nextDay := time.Date(2026, 10, 20, 1, 0, 0, 0, time.UTC)
+
+	assert.Equal(s.T(), "follow:2026-10-19", GroupKey(TypeFollow, uuid.NullUUID{}, morning))
+	assert.Equal(s.T(), GroupKey(TypeFollow, uuid.NullUUID{}, morning), GroupKey(TypeFollow, uuid.NullUUID{}, evening))
+	assert.NotEqual(s.T(), GroupKey(TypeFollow, uuid.NullUUID{}, evening), GroupKey(TypeFollow, uuid.NullUUID{}, nextDay))
+	assert.Equal(s.T(), GroupKey(TypeRechirp, chirpID, morning), GroupKey(TypeRechirp, chirpID, evening))
+	assert.NotEqual(s.T(), GroupKey(TypeRechirp, chirpID, morning), GroupKey(TypeRechirp, uuid.NullUUID{UUID: uuid.New(), Valid: true}, morning))
+	assert.Equal(s.T(), "", GroupKey(TypeMention, chirpID, morning))
+	assert.Equal(s.T(), "", GroupKey(TypeChirpyRed, uuid.NullUUID{}, morning))
+}
+
+func (s *ValidateNotifications) TestSummary() {
+	assert.Equal(s.T(), "@alice followed you", Summary(TypeFollow, "alice", 1))
+	assert.Equal(s.T(), "@alice and 1 other followed you", Summary(TypeFollow, "alice", 2))
+	assert.Equal(s.T(), "@alice and 2 others rechirped your chirp", Summary(TypeRechirp, "alice", 3))
+	assert.Equal(s.T(), "someone mentioned you", Summary(TypeMention, "", 1))
+	assert.Equal(s.T(), "You're now a Chirpy Red member", Summary(TypeChirpyRed, "", 1))
+}
+
+func TestValidateNotifications(t *testing.T) {
+	suite.Run(t, new(ValidateNotifications))
+}
@@ -0,0 +1,40 @@
+package pins
+
+import (
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMax is how many chirps a user can pin when MAX_PINNED_CHIRPS isn't
+// set.
+const DefaultMax = 3
+
+// First moves the pinned chirps to the front of a profile's chirps, in the
+// order they're pinned, and leaves the rest in their order. Pinned chirps
+// missing from chirps, because the viewer can't see them, stay missing.
+func First(chirps []database.Chirp, pinned []uuid.UUID) []database.Chirp {
+	if len(pinned) == 0 {
+		return chirps
+	}
+	byID := make(map[uuid.UUID]database.Chirp, len(chirps))
+	for _, chirp := range chirps {
+		byID[chirp.ID] = chirp
+	}
+	ordered := make([]database.Chirp, 0, len(chirps))
+	isPinned := make(map[uuid.UUID]bool, len(pinned))
+	for _, id := range pinned {
+		chirp, ok := byID[id]
+		if !ok || isPinned[id] {
+			continue
+		}
+		isPinned[id] = true
+		ordered = append(ordered, chirp)
+	}
+	for _, chirp := range chirps {
+		if !isPinned[chirp.ID] {
+			ordered = append(ordered, chirp)
+		}
+	}
+	return ordered
+}
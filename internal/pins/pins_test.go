@@ -0,0 +1,40 @@
+package pins
+
+import (
+	"chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ValidatePins struct {
+	suite.Suite
+}
+
+func ids(chirps []database.Chirp) []uuid.UUID {
+	ret := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ret[i] = chirp.ID
+	}
+	return ret
+}
+
+func (s *ValidatePins) TestFirst() {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	chirps := []database.Chirp{{ID: a}, {ID: b}, {ID: c}, {ID: d}}
+
+	assert.Equal(s.T(), []uuid.UUID{a, b, c, d}, ids(First(chirps, nil)))
+	assert.Equal(s.T(), []uuid.UUID{c, a, b, d}, ids(First(chirps, []uuid.UUID{c})))
+	assert.Equal(s.T(), []uuid.UUID{d, b, a, c}, ids(First(chirps, []uuid.UUID{d, b})))
+}
+
+func (s *ValidatePins) TestHiddenPins() {
+	a, b := uuid.New(), uuid.New()
+	chirps := []database.Chirp{{ID: a}, {ID: b}}
+	assert.Equal(s.T(), []uuid.UUID{b, a}, ids(First(chirps, []uuid.UUID{uuid.New(), b})))
+}
+
+func TestValidatePins(t *testing.T) {
+	suite.Run(t, new(ValidatePins))
+}
@@ -0,0 +1,68 @@
+package polls
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MinOptions = 2
+	MaxOptions = 4
+	// MaxOptionLength is the longest option, in characters.
+	MaxOptionLength = 25
+	MinDuration     = 5 * time.Minute
+	MaxDuration     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrOptionCount     = fmt.Errorf("a poll needs between %d and %d options", MinOptions, MaxOptions)
+	ErrEmptyOption     = errors.New("poll options can't be empty")
+	ErrOptionTooLong   = fmt.Errorf("poll options must be at most %d characters", MaxOptionLength)
+	ErrDuplicateOption = errors.New("poll options must be different")
+	ErrDuration        = fmt.Errorf("a poll must stay open between %s and %s", MinDuration, MaxDuration)
+)
+
+// Validate checks the options of a poll opening at opensAt, which is when
+// its chirp is published, and closing at closesAt. Options are compared
+// after trimming and regardless of case.
+func Validate(options []string, opensAt, closesAt time.Time) error {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return ErrOptionCount
+	}
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return ErrEmptyOption
+		}
+		if utf8.RuneCountInString(option) > MaxOptionLength {
+			return ErrOptionTooLong
+		}
+		key := strings.ToLower(option)
+		if seen[key] {
+			return ErrDuplicateOption
+		}
+		seen[key] = true
+	}
+	open := closesAt.Sub(opensAt)
+	if open < MinDuration || open > MaxDuration {
+		return ErrDuration
+	}
+	return nil
+}
+
+// Closed reports whether a poll closing at closesAt no longer takes votes.
+// Closing is worked out from the time alone, so nothing has to run when a
+// poll closes and a restart can't leave one open.
+func Closed(closesAt, now time.Time) bool {
+	return !now.Before(closesAt)
+}
+
+// ResultsVisible reports whether a viewer is shown the vote counts: once
+// they voted, so the counts don't sway their vote, or once the poll closed.
+func ResultsVisible(voted, closed bool) bool {
+	return voted || closed
+}
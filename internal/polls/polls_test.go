@@ -0,0 +1,54 @@
+package polls
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ValidatePolls struct {
+	suite.Suite
+	now time.Time
+}
+
+func (s *ValidatePolls) SetupTest() {
+	s.now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func (s *ValidatePolls) TestValidate() {
+	day := s.now.Add(24 * time.Hour)
+	assert.NoError(s.T(), Validate([]string{"yes", "no"}, s.now, day))
+	assert.NoError(s.T(), Validate([]string{"a", "b", "c", "d"}, s.now, day))
+	assert.ErrorIs(s.T(), Validate([]string{"yes"}, s.now, day), ErrOptionCount)
+	assert.ErrorIs(s.T(), Validate([]string{"a", "b", "c", "d", "e"}, s.now, day), ErrOptionCount)
+	assert.ErrorIs(s.T(), Validate([]string{"yes", "  "}, s.now, day), ErrEmptyOption)
+	assert.ErrorIs(s.T(), Validate([]string{"yes", strings.Repeat("n", MaxOptionLength+1)}, s.now, day), ErrOptionTooLong)
+	assert.ErrorIs(s.T(), Validate([]string{"Yes", " yes"}, s.now, day), ErrDuplicateOption)
+}
+
+func (s *ValidatePolls) TestDuration() {
+	options := []string{"yes", "no"}
+	assert.NoError(s.T(), Validate(options, s.now, s.now.Add(MinDuration)))
+	assert.NoError(s.T(), Validate(options, s.now, s.now.Add(MaxDuration)))
+	assert.ErrorIs(s.T(), Validate(options, s.now, s.now.Add(MinDuration-time.Second)), ErrDuration)
+	assert.ErrorIs(s.T(), Validate(options, s.now, s.now.Add(MaxDuration+time.Second)), ErrDuration)
+	assert.ErrorIs(s.T(), Validate(options, s.now, s.now.Add(-time.Hour)), ErrDuration)
+}
+
+func (s *ValidatePolls) TestClosed() {
+	assert.False(s.T(), Closed(s.now.Add(time.Second), s.now))
+	assert.True(s.T(), Closed(s.now, s.now))
+	assert.True(s.T(), Closed(s.now.Add(-time.Second), s.now))
+}
+
+func (s *ValidatePolls) TestResultsVisible() {
+	assert.False(s.T(), ResultsVisible(false, false))
+	assert.True(s.T(), ResultsVisible(true, false))
+	assert.True(s.T(), ResultsVisible(false, true))
+}
+
+func TestValidatePolls(t *testing.T) {
+	suite.Run(t, new(ValidatePolls))
+}
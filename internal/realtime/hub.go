@@ -0,0 +1,361 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"chirpy/internal/database"
+	"chirpy/internal/stream"
+	"chirpy/internal/visibility"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Store is what the hub reads from the database.
+type Store interface {
+	GetFollowing(ctx context.Context, followerID uuid.UUID) ([]database.Follow, error)
+	GetHiddenAuthorIds(ctx context.Context, blockerID uuid.UUID) ([]uuid.UUID, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// Hub keeps the sockets open on this instance and routes events to the ones
+// subscribed to them.
+type Hub struct {
+	store    Store
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	conns   map[*conn]struct{}
+	closing bool
+	writers sync.WaitGroup
+}
+
+func NewHub(store Store) *Hub {
+	return &Hub{
+		store: store,
+		conns: make(map[*conn]struct{}),
+	}
+}
+
+// conn is an open socket. Only its write loop writes to ws.
+type conn struct {
+	hub    *Hub
+	ws     *websocket.Conn
+	userID uuid.UUID
+	send   chan ServerMessage
+
+	mu       sync.Mutex
+	channels map[string]bool
+	// who the user follows, loaded when they subscribe to their timeline
+	followees map[uuid.UUID]bool
+	// authors blocked either way or muted when the socket opened
+	hidden map[uuid.UUID]bool
+
+	closeOnce   sync.Once
+	closed      chan struct{}
+	closeCode   int
+	closeReason string
+}
+
+// Serve upgrades the request to a socket for userID and handles it until
+// either side closes it.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, userID uuid.UUID) error {
+	h.mu.Lock()
+	closing := h.closing
+	h.mu.Unlock()
+	if closing {
+		return ErrShuttingDown
+	}
+	hiddenIDs, err := h.store.GetHiddenAuthorIds(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already answered the request
+		return nil
+	}
+	c := &conn{
+		hub:       h,
+		ws:        ws,
+		userID:    userID,
+		send:      make(chan ServerMessage, SendBuffer),
+		channels:  make(map[string]bool),
+		followees: make(map[uuid.UUID]bool),
+		hidden:    make(map[uuid.UUID]bool),
+		closed:    make(chan struct{}),
+	}
+	for _, id := range hiddenIDs {
+		c.hidden[id] = true
+	}
+
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		c.close(websocket.CloseGoingAway, ErrShuttingDown.Error())
+		c.writeClose()
+		return nil
+	}
+	h.conns[c] = struct{}{}
+	h.writers.Add(1)
+	h.mu.Unlock()
+
+	go c.writeLoop()
+	c.readLoop()
+
+	h.mu.Lock()
+	delete(h.conns, c)
+	h.mu.Unlock()
+	return nil
+}
+
+// DispatchChirp sends a chirp event to the sockets subscribed to a channel
+// it belongs in. It doesn't block, so it can be a stream.Broker forward.
+func (h *Hub) DispatchChirp(event stream.Event) {
+	for _, c := range h.open() {
+		c.dispatchChirp(event)
+	}
+}
+
+// DispatchNotification tells the user's sockets subscribed to their
+// notifications about a new one, with their unread count.
+func (h *Hub) DispatchNotification(ctx context.Context, notification Notification) {
+	var subscribed []*conn
+	for _, c := range h.open() {
+		if c.userID == notification.UserID && c.subscribed(ChannelNotifications) {
+			subscribed = append(subscribed, c)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+	unread, err := h.store.CountUnreadNotifications(ctx, notification.UserID)
+	if err != nil {
+		log.Printf("error counting unread notifications for %s: %v", notification.UserID, err)
+		return
+	}
+	data, err := json.Marshal(struct {
+		ID          uuid.UUID `json:"id"`
+		Type        string    `json:"type"`
+		UnreadCount int64     `json:"unread_count"`
+	}{notification.ID, notification.Type, unread})
+	if err != nil {
+		return
+	}
+	for _, c := range subscribed {
+		c.enqueue(ServerMessage{Type: "notification", Channel: ChannelNotifications, Data: data})
+	}
+}
+
+// Shutdown closes every socket with 1001 going away and refuses new ones,
+// waiting until the close frames are written or ctx is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	h.mu.Unlock()
+	for _, c := range h.open() {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	done := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) open() []*conn {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns := make([]*conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// readLoop handles what the client sends until the socket fails or goes
+// quiet for longer than PongWait.
+func (c *conn) readLoop() {
+	c.ws.SetReadLimit(MaxMessageBytes)
+	c.ws.SetReadDeadline(time.Now().Add(PongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(PongWait))
+	})
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			code := websocket.CloseNormalClosure
+			var closeErr *websocket.CloseError
+			if errors.Is(err, websocket.ErrReadLimit) {
+				code = websocket.CloseMessageTooBig
+			} else if !errors.As(err, &closeErr) {
+				code = websocket.CloseGoingAway
+			}
+			c.close(code, "")
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(PongWait))
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.enqueue(ServerMessage{Type: "error", Message: "couldn't decode message"})
+			continue
+		}
+		c.handle(msg)
+	}
+}
+
+func (c *conn) handle(msg ClientMessage) {
+	channel, err := ParseChannel(msg.Channel)
+	if err != nil {
+		c.enqueue(ServerMessage{Type: "error", Channel: msg.Channel, Message: err.Error()})
+		return
+	}
+	switch msg.Action {
+	case "subscribe":
+		if err := c.subscribe(channel); err != nil {
+			c.enqueue(ServerMessage{Type: "error", Channel: channel, Message: err.Error()})
+			return
+		}
+		c.enqueue(ServerMessage{Type: "subscribed", Channel: channel})
+	case "unsubscribe":
+		c.mu.Lock()
+		delete(c.channels, channel)
+		c.mu.Unlock()
+		c.enqueue(ServerMessage{Type: "unsubscribed", Channel: channel})
+	default:
+		c.enqueue(ServerMessage{Type: "error", Channel: channel, Message: "action must be subscribe or unsubscribe"})
+	}
+}
+
+func (c *conn) subscribe(channel string) error {
+	c.mu.Lock()
+	count := len(c.channels)
+	already := c.channels[channel]
+	c.mu.Unlock()
+	if already {
+		return nil
+	}
+	if count >= MaxSubscriptions {
+		return ErrTooManyChannels
+	}
+	var followees map[uuid.UUID]bool
+	if channel == ChannelTimeline {
+		follows, err := c.hub.store.GetFollowing(context.Background(), c.userID)
+		if err != nil {
+			log.Printf("error loading follows for socket of %s: %v", c.userID, err)
+			return errors.New("couldn't subscribe to timeline")
+		}
+		followees = make(map[uuid.UUID]bool, len(follows))
+		for _, follow := range follows {
+			followees[follow.FolloweeID] = true
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channels[channel] = true
+	if followees != nil {
+		c.followees = followees
+	}
+	return nil
+}
+
+func (c *conn) subscribed(channel string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.channels[channel]
+}
+
+func (c *conn) dispatchChirp(event stream.Event) {
+	c.mu.Lock()
+	var channels []string
+	if !c.hidden[event.AuthorID] {
+		isAuthor := event.AuthorID == c.userID
+		if c.channels[ChannelTimeline] && (isAuthor || c.followees[event.AuthorID]) &&
+			visibility.Listed(event.Visibility, isAuthor, c.followees[event.AuthorID]) {
+			channels = append(channels, ChannelTimeline)
+		}
+		if event.Visibility == visibility.Public {
+			for _, tag := range event.Tags {
+				if c.channels[tagPrefix+tag] && !slices.Contains(channels, tagPrefix+tag) {
+					channels = append(channels, tagPrefix+tag)
+				}
+			}
+		}
+	}
+	c.mu.Unlock()
+	for _, channel := range channels {
+		c.enqueue(ServerMessage{Type: event.Type, Channel: channel, Data: event.Data})
+	}
+}
+
+// enqueue queues msg for the write loop. A socket whose queue is full is
+// closed as a slow consumer rather than holding up the hub.
+func (c *conn) enqueue(msg ServerMessage) {
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	select {
+	case c.send <- msg:
+	default:
+		c.close(CloseSlowConsumer, "slow consumer")
+	}
+}
+
+// close has the write loop send a close frame with code and stop, the first
+// call wins.
+func (c *conn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.closed)
+	})
+}
+
+func (c *conn) writeLoop() {
+	defer c.hub.writers.Done()
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			c.writeClose()
+			return
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(WriteWait))
+			if err := c.ws.WriteJSON(msg); err != nil {
+				c.close(websocket.CloseGoingAway, "")
+				c.ws.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(WriteWait)); err != nil {
+				c.close(websocket.CloseGoingAway, "")
+				c.ws.Close()
+				return
+			}
+		}
+	}
+}
+
+// writeClose sends the close frame and closes the socket, which ends the
+// read loop.
+func (c *conn) writeClose() {
+	message := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+	c.ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(WriteWait))
+	c.ws.Close()
+}
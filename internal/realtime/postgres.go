@@ -0,0 +1,44 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Listen delivers the notifications announced on NotificationsChannel,
+// by this instance or another, until ctx is done.
+func (h *Hub) Listen(ctx context.Context, dsn string) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("notification listener: %v", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(NotificationsChannel); err != nil {
+		log.Printf("error listening on %s: %v", NotificationsChannel, err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// nil after a reconnect
+			if n == nil {
+				continue
+			}
+			var notification Notification
+			if err := json.Unmarshal([]byte(n.Extra), &notification); err != nil {
+				log.Printf("error decoding notification: %v", err)
+				continue
+			}
+			h.DispatchNotification(ctx, notification)
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"chirpy/internal/hashtags"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// ChannelNotifications carries the user's new notifications.
+	ChannelNotifications = "notifications"
+	// ChannelTimeline carries chirps from the user and the users they
+	// follow as they're posted and deleted.
+	ChannelTimeline = "timeline"
+	// tagPrefix starts the channel of a hashtag, "tag:golang".
+	tagPrefix = "tag:"
+)
+
+const (
+	// PingInterval is how often a socket is pinged.
+	PingInterval = 30 * time.Second
+	// PongWait is how long a socket can go without answering a ping, or
+	// sending anything, before it's closed.
+	PongWait = 60 * time.Second
+	// WriteWait is how long a write to a socket can take.
+	WriteWait = 10 * time.Second
+	// SendBuffer is how many messages a socket can fall behind before it's
+	// closed as a slow consumer.
+	SendBuffer = 64
+	// MaxSubscriptions is how many channels a socket can subscribe to.
+	MaxSubscriptions = 20
+	// MaxMessageBytes is the largest message a client can send.
+	MaxMessageBytes = 4096
+)
+
+// NotificationsChannel is the Postgres channel notifications are announced
+// on when they're recorded.
+const NotificationsChannel = "chirpy_notifications"
+
+// CloseSlowConsumer is sent to a socket that fell too far behind.
+const CloseSlowConsumer = websocket.CloseTryAgainLater
+
+var (
+	ErrUnknownChannel  = errors.New("channel must be notifications, timeline or tag:<name>")
+	ErrTooManyChannels = fmt.Errorf("a socket can subscribe to at most %d channels", MaxSubscriptions)
+	ErrShuttingDown    = errors.New("server is shutting down")
+)
+
+// ParseChannel checks the name of a channel a client subscribes to, hashtag
+// channels are normalized the way hashtags are stored.
+func ParseChannel(name string) (string, error) {
+	switch name {
+	case ChannelNotifications, ChannelTimeline:
+		return name, nil
+	}
+	tag, ok := strings.CutPrefix(name, tagPrefix)
+	if !ok {
+		return "", ErrUnknownChannel
+	}
+	tag = hashtags.Normalize(tag)
+	tags := hashtags.Extract("#" + tag)
+	if len(tags) != 1 || tags[0] != tag {
+		return "", ErrUnknownChannel
+	}
+	return tagPrefix + tags[0], nil
+}
+
+// ClientMessage is what a client sends, {"action": "subscribe", "channel":
+// "tag:golang"} or "unsubscribe".
+type ClientMessage struct {
+	Action  string `json:"action"`
+	Channel string `json:"channel"`
+}
+
+// ServerMessage is what a socket is sent: "subscribed", "unsubscribed" and
+// "error" answer the client, anything else is an event on Channel.
+type ServerMessage struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Notification is how a recorded notification is announced.
+type Notification struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Type   string    `json:"type"`
+}
@@ -0,0 +1,228 @@
+package realtime
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/stream"
+	"chirpy/internal/visibility"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ValidateParseChannel struct {
+	suite.Suite
+}
+
+func (s *ValidateParseChannel) TestChannels() {
+	for _, name := range []string{ChannelNotifications, ChannelTimeline, "tag:golang"} {
+		channel, err := ParseChannel(name)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), name, channel)
+	}
+	channel, err := ParseChannel("tag:#GoLang")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "tag:golang", channel)
+	for _, name := range []string{"", "everything", "tag:", "tag:123", "tag:two words", "Timeline"} {
+		_, err := ParseChannel(name)
+		assert.ErrorIs(s.T(), err, ErrUnknownChannel, name)
+	}
+}
+
+func TestValidateParseChannel(t *testing.T) {
+	suite.Run(t, new(ValidateParseChannel))
+}
+
+type fakeStore struct {
+	following map[uuid.UUID][]uuid.UUID
+	hidden    map[uuid.UUID][]uuid.UUID
+	unread    int64
+}
+
+func (f fakeStore) GetFollowing(ctx context.Context, followerID uuid.UUID) ([]database.Follow, error) {
+	var follows []database.Follow
+	for _, id := range f.following[followerID] {
+		follows = append(follows, database.Follow{FollowerID: followerID, FolloweeID: id})
+	}
+	return follows, nil
+}
+
+func (f fakeStore) GetHiddenAuthorIds(ctx context.Context, blockerID uuid.UUID) ([]uuid.UUID, error) {
+	return f.hidden[blockerID], nil
+}
+
+func (f fakeStore) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return f.unread, nil
+}
+
+type ValidateHub struct {
+	suite.Suite
+	user     uuid.UUID
+	followee uuid.UUID
+	blocked  uuid.UUID
+	hub      *Hub
+	server   *httptest.Server
+}
+
+func (s *ValidateHub) SetupTest() {
+	s.user = uuid.New()
+	s.followee = uuid.New()
+	s.blocked = uuid.New()
+	s.hub = NewHub(fakeStore{
+		following: map[uuid.UUID][]uuid.UUID{s.user: {s.followee}},
+		hidden:    map[uuid.UUID][]uuid.UUID{s.user: {s.blocked}},
+		unread:    3,
+	})
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.hub.Serve(w, r, s.user); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+}
+
+func (s *ValidateHub) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ValidateHub) dial() *websocket.Conn {
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.server.URL, "http"), nil)
+	s.Require().NoError(err)
+	return ws
+}
+
+func (s *ValidateHub) subscribe(ws *websocket.Conn, channel string) {
+	s.Require().NoError(ws.WriteJSON(ClientMessage{Action: "subscribe", Channel: channel}))
+	reply := s.read(ws)
+	s.Require().Equal("subscribed", reply.Type)
+}
+
+func (s *ValidateHub) read(ws *websocket.Conn) ServerMessage {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var msg ServerMessage
+	s.Require().NoError(ws.ReadJSON(&msg))
+	return msg
+}
+
+func chirpEvent(author uuid.UUID, vis string, tags ...string) stream.Event {
+	return stream.Event{
+		Type:       stream.TypeCreated,
+		ChirpID:    uuid.New(),
+		AuthorID:   author,
+		Visibility: vis,
+		Tags:       tags,
+		Data:       json.RawMessage(`{}`),
+	}
+}
+
+func (s *ValidateHub) TestTimeline() {
+	ws := s.dial()
+	defer ws.Close()
+	s.subscribe(ws, ChannelTimeline)
+
+	s.hub.DispatchChirp(chirpEvent(uuid.New(), visibility.Public))
+	s.hub.DispatchChirp(chirpEvent(s.blocked, visibility.Public))
+	s.hub.DispatchChirp(chirpEvent(s.followee, visibility.Unlisted))
+	followersOnly := chirpEvent(s.followee, visibility.FollowersOnly)
+	s.hub.DispatchChirp(followersOnly)
+	own := chirpEvent(s.user, visibility.Unlisted)
+	s.hub.DispatchChirp(own)
+
+	msg := s.read(ws)
+	assert.Equal(s.T(), stream.TypeCreated, msg.Type)
+	assert.Equal(s.T(), ChannelTimeline, msg.Channel)
+	assert.Equal(s.T(), ChannelTimeline, s.read(ws).Channel)
+
+	// nothing else was queued
+	s.Require().NoError(ws.WriteJSON(ClientMessage{Action: "unsubscribe", Channel: ChannelTimeline}))
+	assert.Equal(s.T(), "unsubscribed", s.read(ws).Type)
+}
+
+func (s *ValidateHub) TestTag() {
+	ws := s.dial()
+	defer ws.Close()
+	s.subscribe(ws, "tag:golang")
+
+	s.hub.DispatchChirp(chirpEvent(uuid.New(), visibility.FollowersOnly, "golang"))
+	s.hub.DispatchChirp(chirpEvent(uuid.New(), visibility.Public, "rust"))
+	s.hub.DispatchChirp(chirpEvent(uuid.New(), visibility.Public, "rust", "golang"))
+
+	msg := s.read(ws)
+	assert.Equal(s.T(), "tag:golang", msg.Channel)
+}
+
+func (s *ValidateHub) TestNotifications() {
+	ws := s.dial()
+	defer ws.Close()
+	s.subscribe(ws, ChannelNotifications)
+
+	s.hub.DispatchNotification(context.Background(), Notification{ID: uuid.New(), UserID: uuid.New(), Type: "follow"})
+	id := uuid.New()
+	s.hub.DispatchNotification(context.Background(), Notification{ID: id, UserID: s.user, Type: "follow"})
+
+	msg := s.read(ws)
+	assert.Equal(s.T(), "notification", msg.Type)
+	var data struct {
+		ID          uuid.UUID `json:"id"`
+		UnreadCount int64     `json:"unread_count"`
+	}
+	s.Require().NoError(json.Unmarshal(msg.Data, &data))
+	assert.Equal(s.T(), id, data.ID)
+	assert.Equal(s.T(), int64(3), data.UnreadCount)
+}
+
+func (s *ValidateHub) TestBadMessages() {
+	ws := s.dial()
+	defer ws.Close()
+	s.Require().NoError(ws.WriteMessage(websocket.TextMessage, []byte("hello")))
+	assert.Equal(s.T(), "error", s.read(ws).Type)
+	s.Require().NoError(ws.WriteJSON(ClientMessage{Action: "subscribe", Channel: "everything"}))
+	assert.Equal(s.T(), "error", s.read(ws).Type)
+	s.Require().NoError(ws.WriteJSON(ClientMessage{Action: "listen", Channel: ChannelTimeline}))
+	assert.Equal(s.T(), "error", s.read(ws).Type)
+}
+
+func (s *ValidateHub) TestSlowConsumer() {
+	ws := s.dial()
+	defer ws.Close()
+	s.subscribe(ws, ChannelTimeline)
+
+	// stop reading and flood the socket's queue
+	for i := 0; i < SendBuffer*100; i++ {
+		s.hub.DispatchChirp(chirpEvent(s.user, visibility.Public))
+	}
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var err error
+	for err == nil {
+		_, _, err = ws.ReadMessage()
+	}
+	assert.True(s.T(), websocket.IsCloseError(err, CloseSlowConsumer), err)
+}
+
+func (s *ValidateHub) TestShutdown() {
+	ws := s.dial()
+	defer ws.Close()
+	s.subscribe(ws, ChannelTimeline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.Require().NoError(s.hub.Shutdown(ctx))
+
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := ws.ReadMessage()
+	assert.True(s.T(), websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.server.URL, "http"), nil)
+	assert.Error(s.T(), err)
+	assert.Equal(s.T(), http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestValidateHub(t *testing.T) {
+	suite.Run(t, new(ValidateHub))
+}
@@ -0,0 +1,96 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ReasonSpam           = "spam"
+	ReasonHarassment     = "harassment"
+	ReasonHate           = "hate"
+	ReasonViolence       = "violence"
+	ReasonMisinformation = "misinformation"
+	ReasonOther          = "other"
+)
+
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+const (
+	TargetChirp = "chirp"
+	TargetUser  = "user"
+)
+
+// Actions a moderator can take on a report, each resolves it with the
+// matching outcome.
+const (
+	ActionDismiss     = "dismiss"
+	ActionDeleteChirp = "delete_chirp"
+	ActionSuspendUser = "suspend_user"
+)
+
+// Actions moderators take on users directly, outside of a report. They're
+// recorded in moderation_actions next to the report actions.
+const (
+	ActionLiftSuspension = "lift_suspension"
+	ActionShadowBan      = "shadow_ban"
+	ActionLiftShadowBan  = "lift_shadow_ban"
+)
+
+const (
+	OutcomeDismissed     = "dismissed"
+	OutcomeChirpDeleted  = "chirp_deleted"
+	OutcomeUserSuspended = "user_suspended"
+)
+
+const (
+	MaxDetailsLength = 1000
+	// DefaultSuspension is how long suspend_user suspends for when the
+	// moderator doesn't say.
+	DefaultSuspension = 7 * 24 * time.Hour
+)
+
+var reasons = map[string]bool{
+	ReasonSpam:           true,
+	ReasonHarassment:     true,
+	ReasonHate:           true,
+	ReasonViolence:       true,
+	ReasonMisinformation: true,
+	ReasonOther:          true,
+}
+
+var outcomes = map[string]string{
+	ActionDismiss:     OutcomeDismissed,
+	ActionDeleteChirp: OutcomeChirpDeleted,
+	ActionSuspendUser: OutcomeUserSuspended,
+}
+
+var (
+	ErrInvalidReason  = errors.New("reason must be one of spam, harassment, hate, violence, misinformation or other")
+	ErrDetailsTooLong = fmt.Errorf("details can be at most %d characters", MaxDetailsLength)
+	ErrNoTarget       = errors.New("report exactly one of chirp_id or user_id")
+	ErrInvalidAction  = errors.New("action must be one of dismiss, delete_chirp or suspend_user")
+	ErrNotAChirp      = errors.New("delete_chirp needs a report against a chirp")
+)
+
+func ValidReason(reason string) bool {
+	return reasons[reason]
+}
+
+// Outcome returns the outcome a report is resolved with by action.
+func Outcome(action string) (string, error) {
+	outcome, ok := outcomes[action]
+	if !ok {
+		return "", ErrInvalidAction
+	}
+	return outcome, nil
+}
+
+// ValidTargetType reports whether targetType can filter the queue.
+func ValidTargetType(targetType string) bool {
+	return targetType == TargetChirp || targetType == TargetUser
+}
@@ -0,0 +1,39 @@
+package reports
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ValidateReports struct {
+	suite.Suite
+}
+
+func (s *ValidateReports) TestValidReason() {
+	for _, reason := range []string{ReasonSpam, ReasonHarassment, ReasonHate, ReasonViolence, ReasonMisinformation, ReasonOther} {
+		assert.True(s.T(), ValidReason(reason), reason)
+	}
+	for _, reason := range []string{"", "Spam", "boring"} {
+		assert.False(s.T(), ValidReason(reason), reason)
+	}
+}
+
+func (s *ValidateReports) TestOutcome() {
+	cases := map[string]string{
+		ActionDismiss:     OutcomeDismissed,
+		ActionDeleteChirp: OutcomeChirpDeleted,
+		ActionSuspendUser: OutcomeUserSuspended,
+	}
+	for action, expected := range cases {
+		outcome, err := Outcome(action)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), expected, outcome)
+	}
+	_, err := Outcome("ban")
+	assert.ErrorIs(s.T(), err, ErrInvalidAction)
+}
+
+func TestValidateReports(t *testing.T) {
+	suite.Run(t, new(ValidateReports))
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	DefaultInterval = 15 * time.Second
+	// MaxLead is how far ahead a chirp can be scheduled.
+	MaxLead = 365 * 24 * time.Hour
+	// FirstRetryDelay is how long a chirp that failed to publish waits
+	// before it's tried again, doubling with every failure up to
+	// MaxRetryDelay.
+	FirstRetryDelay = time.Minute
+	MaxRetryDelay   = 6 * time.Hour
+)
+
+var (
+	ErrInPast = errors.New("publish_at must be in the future")
+	ErrTooFar = errors.New("publish_at must be within a year")
+	ErrNotRed = errors.New("scheduling chirps requires Chirpy Red")
+)
+
+// Validate checks that a chirp can be scheduled to publish at publishAt.
+func Validate(publishAt, now time.Time) error {
+	if !publishAt.After(now) {
+		return ErrInPast
+	}
+	if publishAt.Sub(now) > MaxLead {
+		return ErrTooFar
+	}
+	return nil
+}
+
+// RetryDelay is how long a chirp waits to be published again after its
+// attempts-th failure.
+func RetryDelay(attempts int32) time.Duration {
+	delay := FirstRetryDelay
+	for i := int32(1); i < attempts && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxRetryDelay)
+}
+
+// Publisher publishes scheduled chirps once their publish_at has passed.
+// Each chirp is claimed with FOR UPDATE SKIP LOCKED and published in the
+// same transaction as OnPublish runs in, so with several instances running
+// a Publisher every chirp is still published exactly once. A chirp whose
+// OnPublish fails stays scheduled and is put off for RetryDelay, so the
+// chirps due after it are published in the meantime.
+type Publisher struct {
+	DB      *sql.DB
+	Queries *database.Queries
+	// OnPublish does what posting a chirp does besides storing it, like
+	// extracting tags and fanning it out to timelines. It's given queries
+	// bound to the publishing transaction.
+	OnPublish func(ctx context.Context, queries *database.Queries, chirp database.Chirp) error
+	// Published is called with each chirp once its publishing is
+	// committed, for what can't be rolled back like streaming it.
+	Published func(ctx context.Context, chirp database.Chirp)
+	Interval  time.Duration
+}
+
+// PublishNext publishes the scheduled chirp that is due the longest. It
+// reports whether there was one, including one that failed to publish and
+// was put off.
+func (p Publisher) PublishNext(ctx context.Context) (bool, error) {
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := p.Queries.WithTx(tx)
+	chirp, err := qtx.PublishDueChirp(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if p.OnPublish != nil {
+		if err := p.OnPublish(ctx, qtx, chirp); err != nil {
+			return p.postpone(ctx, tx, chirp, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if p.Published != nil {
+		p.Published(ctx, chirp)
+	}
+	return true, nil
+}
+
+// postpone rolls back publishing chirp, which failed with err, and puts it
+// off to be retried.
+func (p Publisher) postpone(ctx context.Context, tx *sql.Tx, chirp database.Chirp, err error) (bool, error) {
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return false, errors.Join(err, rollbackErr)
+	}
+	attempts := chirp.PublishAttempts + 1
+	delay := RetryDelay(attempts)
+	postponeErr := p.Queries.PostponeScheduledChirp(ctx, database.PostponeScheduledChirpParams{
+		DelaySeconds: int32(delay / time.Second),
+		ID:           chirp.ID,
+	})
+	if postponeErr != nil {
+		return false, errors.Join(err, postponeErr)
+	}
+	return true, fmt.Errorf("chirp %s failed to publish %d times, retrying in %v: %w", chirp.ID, attempts, delay, err)
+}
+
+// Run publishes due chirps every Interval until ctx is done.
+func (p Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		for {
+			published, err := p.PublishNext(ctx)
+			if err != nil {
+				log.Printf("error publishing scheduled chirp: %v", err)
+			}
+			// a chirp that failed was put off, the next one can go
+			if !published {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
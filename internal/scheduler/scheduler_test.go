@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ValidateSchedule struct {
+	suite.Suite
+}
+
+func (s *ValidateSchedule) TestValidate() {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(s.T(), Validate(now.Add(time.Minute), now))
+	assert.NoError(s.T(), Validate(now.Add(MaxLead), now))
+	assert.ErrorIs(s.T(), Validate(now, now), ErrInPast)
+	assert.ErrorIs(s.T(), Validate(now.Add(-time.Hour), now), ErrInPast)
+	assert.ErrorIs(s.T(), Validate(now.Add(MaxLead+time.Second), now), ErrTooFar)
+}
+
+func (s *ValidateSchedule) TestRetryDelay() {
+	assert.Equal(s.T(), FirstRetryDelay, RetryDelay(1))
+	assert.Equal(s.T(), 2*FirstRetryDelay, RetryDelay(2))
+	assert.Equal(s.T(), 8*FirstRetryDelay, RetryDelay(4))
+	assert.Equal(s.T(), MaxRetryDelay, RetryDelay(20))
+	assert.Equal(s.T(), MaxRetryDelay, RetryDelay(1000))
+}
+
+func TestValidateSchedule(t *testing.T) {
+	suite.Run(t, new(ValidateSchedule))
+}
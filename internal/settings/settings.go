@@ -0,0 +1,217 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"chirpy/internal/database"
+	"chirpy/internal/notifications"
+	"chirpy/internal/visibility"
+
+	"github.com/google/uuid"
+)
+
+// Version is the version of the settings resource this server speaks. New
+// fields are added without changing it: they come with a default that keeps
+// the old behavior, and a PATCH only changes the fields it sends, so a
+// client that doesn't know a field never resets it. Version goes up when a
+// field is removed or its meaning changes, and clients send the version
+// they were written for with their changes.
+const Version = 1
+
+// Who may reach a user, for mentions and direct messages. Following means
+// the people the user follows.
+const (
+	Everyone  = "everyone"
+	Following = "following"
+	Nobody    = "nobody"
+)
+
+var (
+	ErrUnsupportedVersion = fmt.Errorf("settings version must be between 1 and %d", Version)
+	ErrInvalidAudience    = errors.New("mentions_from and messages_from must be everyone, following or nobody")
+)
+
+// CheckVersion checks the version a client's changes were written for, 0
+// when it didn't say means the current one.
+func CheckVersion(version int) error {
+	if version < 0 || version > Version {
+		return ErrUnsupportedVersion
+	}
+	return nil
+}
+
+// InAppTypes are the notification types that can be turned off in-app.
+var InAppTypes = []string{
+	notifications.TypeFollow,
+	notifications.TypeMention,
+	notifications.TypeQuote,
+	notifications.TypeRechirp,
+	notifications.TypeChirpyRed,
+	notifications.TypeReportResolved,
+}
+
+// EmailTypes are the notification types that are emailed, in the digest.
+var EmailTypes = []string{
+	notifications.TypeFollow,
+	notifications.TypeMention,
+}
+
+// Settings are a user's notification and privacy preferences. Notification
+// types are on unless they're in a Disabled list, so new types reach
+// everyone until they turn them off.
+type Settings struct {
+	InAppDisabled       []string
+	EmailDisabled       []string
+	DiscoverableByEmail bool
+	MentionsFrom        string
+	MessagesFrom        string
+	DefaultVisibility   string
+}
+
+// Default is what a user who never changed their settings has.
+func Default() Settings {
+	return Settings{
+		InAppDisabled:     []string{},
+		EmailDisabled:     []string{},
+		MentionsFrom:      Everyone,
+		MessagesFrom:      Everyone,
+		DefaultVisibility: visibility.Public,
+	}
+}
+
+// From turns a user_settings row into Settings.
+func From(row database.UserSetting) Settings {
+	return Settings{
+		InAppDisabled:       row.InAppDisabled,
+		EmailDisabled:       row.EmailDisabled,
+		DiscoverableByEmail: row.DiscoverableByEmail,
+		MentionsFrom:        row.MentionsFrom,
+		MessagesFrom:        row.MessagesFrom,
+		DefaultVisibility:   row.DefaultVisibility,
+	}
+}
+
+// Load returns a user's settings, the defaults until they change one.
+func Load(ctx context.Context, queries *database.Queries, userID uuid.UUID) (Settings, error) {
+	row, err := queries.GetUserSettings(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Default(), nil
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+	return From(row), nil
+}
+
+// LoadForUpdate returns a user's settings like Load, locking them until the
+// transaction queries are bound to ends so concurrent changes are made one
+// after the other. Users with the defaults get a row to lock.
+func LoadForUpdate(ctx context.Context, queries *database.Queries, userID uuid.UUID) (Settings, error) {
+	if err := queries.CreateDefaultUserSettings(ctx, userID); err != nil {
+		return Settings{}, err
+	}
+	row, err := queries.GetUserSettingsForUpdate(ctx, userID)
+	if err != nil {
+		return Settings{}, err
+	}
+	return From(row), nil
+}
+
+// InApp reports whether notifications of a type are shown in-app.
+func (s Settings) InApp(notificationType string) bool {
+	return !slices.Contains(s.InAppDisabled, notificationType)
+}
+
+// Email reports whether notifications of a type are emailed.
+func (s Settings) Email(notificationType string) bool {
+	return !slices.Contains(s.EmailDisabled, notificationType)
+}
+
+// Patch is a change to some settings, the fields left nil are kept.
+// Notification types not in a map are kept too.
+type Patch struct {
+	InApp               map[string]bool
+	Email               map[string]bool
+	DiscoverableByEmail *bool
+	MentionsFrom        *string
+	MessagesFrom        *string
+	DefaultVisibility   *string
+}
+
+// Apply validates p and returns s with it applied.
+func (p Patch) Apply(s Settings) (Settings, error) {
+	var err error
+	if s.InAppDisabled, err = toggle(s.InAppDisabled, p.InApp, InAppTypes, "in-app"); err != nil {
+		return Settings{}, err
+	}
+	if s.EmailDisabled, err = toggle(s.EmailDisabled, p.Email, EmailTypes, "email"); err != nil {
+		return Settings{}, err
+	}
+	if p.DiscoverableByEmail != nil {
+		s.DiscoverableByEmail = *p.DiscoverableByEmail
+	}
+	if p.MentionsFrom != nil {
+		if !validAudience(*p.MentionsFrom) {
+			return Settings{}, ErrInvalidAudience
+		}
+		s.MentionsFrom = *p.MentionsFrom
+	}
+	if p.MessagesFrom != nil {
+		if !validAudience(*p.MessagesFrom) {
+			return Settings{}, ErrInvalidAudience
+		}
+		s.MessagesFrom = *p.MessagesFrom
+	}
+	if p.DefaultVisibility != nil {
+		// unlike a chirp's, the default has to be spelled out
+		if *p.DefaultVisibility == "" {
+			return Settings{}, visibility.ErrInvalid
+		}
+		if s.DefaultVisibility, err = visibility.Parse(*p.DefaultVisibility); err != nil {
+			return Settings{}, err
+		}
+	}
+	return s, nil
+}
+
+// toggle turns the types in changes on or off in disabled, the returned
+// list is sorted so equal settings are stored the same.
+func toggle(disabled []string, changes map[string]bool, types []string, kind string) ([]string, error) {
+	result := slices.Clone(disabled)
+	for notificationType, on := range changes {
+		if !slices.Contains(types, notificationType) {
+			return nil, fmt.Errorf("%s notifications can't be set for %q", kind, notificationType)
+		}
+		result = slices.DeleteFunc(result, func(t string) bool { return t == notificationType })
+		if !on {
+			result = append(result, notificationType)
+		}
+	}
+	slices.Sort(result)
+	if result == nil {
+		result = []string{}
+	}
+	return result, nil
+}
+
+func validAudience(audience string) bool {
+	return audience == Everyone || audience == Following || audience == Nobody
+}
+
+// Allows reports whether a user whose audience setting is audience can be
+// reached by someone, who may be themself or someone they follow.
+func Allows(audience string, isSelf, isFollowed bool) bool {
+	switch {
+	case isSelf:
+		return true
+	case audience == Following:
+		return isFollowed
+	case audience == Nobody:
+		return false
+	}
+	return true
+}
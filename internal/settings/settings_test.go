@@ -0,0 +1,92 @@
+package settings
+
+import (
+	"chirpy/internal/notifications"
+	"chirpy/internal/visibility"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ValidatePatch struct {
+	suite.Suite
+}
+
+func (s *ValidatePatch) TestDefault() {
+	defaults := Default()
+	for _, notificationType := range InAppTypes {
+		assert.True(s.T(), defaults.InApp(notificationType))
+	}
+	for _, notificationType := range EmailTypes {
+		assert.True(s.T(), defaults.Email(notificationType))
+	}
+	assert.False(s.T(), defaults.DiscoverableByEmail)
+	assert.Equal(s.T(), Everyone, defaults.MentionsFrom)
+	assert.Equal(s.T(), Everyone, defaults.MessagesFrom)
+	assert.Equal(s.T(), visibility.Public, defaults.DefaultVisibility)
+}
+
+func (s *ValidatePatch) TestApply() {
+	discoverable := true
+	following := Following
+	followersOnly := visibility.FollowersOnly
+	updated, err := Patch{
+		InApp:               map[string]bool{notifications.TypeRechirp: false, notifications.TypeFollow: false},
+		Email:               map[string]bool{notifications.TypeMention: false},
+		DiscoverableByEmail: &discoverable,
+		MessagesFrom:        &following,
+		DefaultVisibility:   &followersOnly,
+	}.Apply(Default())
+	s.Require().NoError(err)
+	assert.Equal(s.T(), []string{notifications.TypeFollow, notifications.TypeRechirp}, updated.InAppDisabled)
+	assert.False(s.T(), updated.InApp(notifications.TypeRechirp))
+	assert.True(s.T(), updated.InApp(notifications.TypeMention))
+	assert.False(s.T(), updated.Email(notifications.TypeMention))
+	assert.True(s.T(), updated.Email(notifications.TypeFollow))
+	assert.True(s.T(), updated.DiscoverableByEmail)
+	assert.Equal(s.T(), Everyone, updated.MentionsFrom)
+	assert.Equal(s.T(), Following, updated.MessagesFrom)
+	assert.Equal(s.T(), visibility.FollowersOnly, updated.DefaultVisibility)
+
+	// turning a type back on, the rest is kept
+	updated, err = Patch{InApp: map[string]bool{notifications.TypeFollow: true}}.Apply(updated)
+	s.Require().NoError(err)
+	assert.Equal(s.T(), []string{notifications.TypeRechirp}, updated.InAppDisabled)
+	assert.True(s.T(), updated.DiscoverableByEmail)
+}
+
+func (s *ValidatePatch) TestInvalid() {
+	friends := "friends"
+	empty := ""
+	private := "private"
+	for _, patch := range []Patch{
+		{InApp: map[string]bool{"likes": false}},
+		{Email: map[string]bool{notifications.TypeRechirp: false}},
+		{MentionsFrom: &friends},
+		{MessagesFrom: &empty},
+		{DefaultVisibility: &empty},
+		{DefaultVisibility: &private},
+	} {
+		_, err := patch.Apply(Default())
+		assert.Error(s.T(), err)
+	}
+}
+
+func (s *ValidatePatch) TestVersion() {
+	assert.NoError(s.T(), CheckVersion(0))
+	assert.NoError(s.T(), CheckVersion(Version))
+	assert.ErrorIs(s.T(), CheckVersion(Version+1), ErrUnsupportedVersion)
+	assert.ErrorIs(s.T(), CheckVersion(-1), ErrUnsupportedVersion)
+}
+
+func (s *ValidatePatch) TestAllows() {
+	assert.True(s.T(), Allows(Everyone, false, false))
+	assert.False(s.T(), Allows(Following, false, false))
+	assert.True(s.T(), Allows(Following, false, true))
+	assert.False(s.T(), Allows(Nobody, false, true))
+	assert.True(s.T(), Allows(Nobody, true, false))
+}
+
+func TestValidatePatch(t *testing.T) {
+	suite.Run(t, new(ValidatePatch))
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps uploaded files. Keys are slash separated paths like
+// "chirps/<chirp id>/<attachment id>.png".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get returns ErrNotFound when nothing is stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds when nothing is stored under key.
+	Delete(ctx context.Context, key string) error
+	// URL is where clients download the object from.
+	URL(key string) string
+}
@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultChannel is the Postgres channel instances share events on.
+const DefaultChannel = "chirp_events"
+
+// Postgres is the Fanout for several instances: events are sent with
+// NOTIFY and every instance, this one included, delivers what it LISTENs
+// to into its Broker. Events are small, the payload limit is 8000 bytes.
+type Postgres struct {
+	DB      *sql.DB
+	DSN     string
+	Channel string
+	Broker  *Broker
+}
+
+func (p Postgres) Publish(ctx context.Context, event Event) error {
+	if event.ID == 0 {
+		event.ID = p.Broker.NextID()
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = p.DB.ExecContext(ctx, "select pg_notify($1, $2)", p.Channel, string(payload))
+	return err
+}
+
+// Run listens for events until ctx is done. Events sent while the
+// connection is being reestablished are lost.
+func (p Postgres) Run(ctx context.Context) {
+	listener := pq.NewListener(p.DSN, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("chirp stream listener: %v", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(p.Channel); err != nil {
+		log.Printf("error listening on %s: %v", p.Channel, err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			// nil after a reconnect
+			if notification == nil {
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				log.Printf("error decoding chirp event: %v", err)
+				continue
+			}
+			p.Broker.Deliver(event)
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
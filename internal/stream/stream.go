@@ -0,0 +1,169 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TypeCreated = "chirp.created"
+	TypeDeleted = "chirp.deleted"
+)
+
+const (
+	// DefaultReplaySize is how many recent events are kept for clients
+	// resuming with Last-Event-ID.
+	DefaultReplaySize = 500
+	// DefaultMaxStreams is how many streams can be open at once when
+	// MAX_CHIRP_STREAMS isn't set.
+	DefaultMaxStreams = 1000
+	// HeartbeatInterval is how often an idle stream gets a comment, so
+	// proxies don't close it.
+	HeartbeatInterval = 15 * time.Second
+	// subscriberBuffer is how many events a stream can fall behind before
+	// it's dropped.
+	subscriberBuffer = 64
+)
+
+var ErrTooManyStreams = errors.New("too many open streams, try again later")
+
+// Event is a change to the chirps clients can see. IDs increase with time
+// so they can be compared across instances, Data is what's sent to clients.
+// Visibility and Tags let subscribers decide who the chirp goes to.
+type Event struct {
+	ID         uint64          `json:"id"`
+	Type       string          `json:"type"`
+	ChirpID    uuid.UUID       `json:"chirp_id"`
+	AuthorID   uuid.UUID       `json:"author_id"`
+	Visibility string          `json:"visibility"`
+	Tags       []string        `json:"tags,omitempty"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Fanout sends an event to the streams of every instance.
+type Fanout interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Subscription is an open stream. C is closed when the stream fell too far
+// behind, the client reconnects and resumes from the replay buffer.
+type Subscription struct {
+	C <-chan Event
+	c chan Event
+}
+
+// Broker delivers events to the streams open on this instance and keeps the
+// most recent ones for resuming. On its own it's the in-process Fanout.
+type Broker struct {
+	mu         sync.Mutex
+	replay     []Event
+	replaySize int
+	maxStreams int
+	subs       map[*Subscription]struct{}
+	forwards   []func(Event)
+	lastID     uint64
+}
+
+func NewBroker(replaySize, maxStreams int) *Broker {
+	return &Broker{
+		replaySize: replaySize,
+		maxStreams: maxStreams,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// NextID returns an event id later than any this broker has seen, from the
+// clock so that instances sharing events through Postgres agree on order.
+func (b *Broker) NextID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := uint64(time.Now().UnixNano())
+	if id <= b.lastID {
+		id = b.lastID + 1
+	}
+	b.lastID = id
+	return id
+}
+
+// Publish delivers event to this instance's streams, giving it an id first.
+func (b *Broker) Publish(ctx context.Context, event Event) error {
+	if event.ID == 0 {
+		event.ID = b.NextID()
+	}
+	b.Deliver(event)
+	return nil
+}
+
+// Forward has f called with every event delivered from now on, after the
+// streams have it. f must not block.
+func (b *Broker) Forward(f func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.forwards = append(b.forwards, f)
+}
+
+// Deliver keeps event for replay and sends it to every open stream. Streams
+// that can't keep up are closed rather than holding up the others.
+func (b *Broker) Deliver(event Event) {
+	for _, f := range b.deliver(event) {
+		f(event)
+	}
+}
+
+func (b *Broker) deliver(event Event) []func(Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if event.ID > b.lastID {
+		b.lastID = event.ID
+	}
+	b.replay = append(b.replay, event)
+	if len(b.replay) > b.replaySize {
+		b.replay = b.replay[len(b.replay)-b.replaySize:]
+	}
+	for sub := range b.subs {
+		select {
+		case sub.c <- event:
+		default:
+			delete(b.subs, sub)
+			close(sub.c)
+		}
+	}
+	return b.forwards
+}
+
+// Subscribe opens a stream, returning with it the kept events after
+// lastEventID to send first. A lastEventID of 0 replays nothing.
+func (b *Broker) Subscribe(lastEventID uint64) (*Subscription, []Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subs) >= b.maxStreams {
+		return nil, nil, ErrTooManyStreams
+	}
+	var missed []Event
+	if lastEventID != 0 {
+		for _, event := range b.replay {
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+	c := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: c, c: c}
+	b.subs[sub] = struct{}{}
+	return sub, missed, nil
+}
+
+// Unsubscribe closes a stream, it's safe to call on one that was dropped.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ValidateBroker struct {
+	suite.Suite
+}
+
+func event(chirpID uuid.UUID) Event {
+	return Event{Type: TypeCreated, ChirpID: chirpID, AuthorID: uuid.New()}
+}
+
+func (s *ValidateBroker) TestDeliver() {
+	broker := NewBroker(10, 10)
+	sub, missed, err := broker.Subscribe(0)
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), missed)
+
+	chirpID := uuid.New()
+	assert.NoError(s.T(), broker.Publish(context.Background(), event(chirpID)))
+	received := <-sub.C
+	assert.Equal(s.T(), chirpID, received.ChirpID)
+	assert.NotZero(s.T(), received.ID)
+
+	broker.Unsubscribe(sub)
+	_, ok := <-sub.C
+	assert.False(s.T(), ok)
+	broker.Unsubscribe(sub)
+}
+
+func (s *ValidateBroker) TestIDsIncrease() {
+	broker := NewBroker(10, 10)
+	last := broker.NextID()
+	for i := 0; i < 100; i++ {
+		id := broker.NextID()
+		assert.Greater(s.T(), id, last)
+		last = id
+	}
+	broker.Deliver(Event{ID: last + 1000})
+	assert.Greater(s.T(), broker.NextID(), last+1000)
+}
+
+func (s *ValidateBroker) TestReplay() {
+	broker := NewBroker(3, 10)
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		id := broker.NextID()
+		ids = append(ids, id)
+		broker.Deliver(Event{ID: id, Type: TypeCreated})
+	}
+
+	_, missed, err := broker.Subscribe(ids[2])
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), missed, 2)
+	assert.Equal(s.T(), ids[3], missed[0].ID)
+	assert.Equal(s.T(), ids[4], missed[1].ID)
+
+	// older than the buffer, what's kept is replayed
+	_, missed, err = broker.Subscribe(ids[0])
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), missed, 3)
+
+	_, missed, err = broker.Subscribe(ids[4])
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), missed)
+}
+
+func (s *ValidateBroker) TestMaxStreams() {
+	broker := NewBroker(10, 2)
+	first, _, err := broker.Subscribe(0)
+	assert.NoError(s.T(), err)
+	_, _, err = broker.Subscribe(0)
+	assert.NoError(s.T(), err)
+	_, _, err = broker.Subscribe(0)
+	assert.ErrorIs(s.T(), err, ErrTooManyStreams)
+
+	broker.Unsubscribe(first)
+	_, _, err = broker.Subscribe(0)
+	assert.NoError(s.T(), err)
+}
+
+func (s *ValidateBroker) TestSlowStreamDropped() {
+	broker := NewBroker(10, 10)
+	slow, _, err := broker.Subscribe(0)
+	assert.NoError(s.T(), err)
+	for i := 0; i <= subscriberBuffer; i++ {
+		broker.Deliver(Event{ID: uint64(i + 1)})
+	}
+	received := 0
+	for range slow.C {
+		received++
+	}
+	assert.Equal(s.T(), subscriberBuffer, received)
+
+	_, _, err = broker.Subscribe(0)
+	assert.NoError(s.T(), err)
+}
+
+func (s *ValidateBroker) TestForward() {
+	broker := NewBroker(10, 10)
+	var forwarded []Event
+	broker.Forward(func(event Event) {
+		forwarded = append(forwarded, event)
+	})
+	chirpID := uuid.New()
+	assert.NoError(s.T(), broker.Publish(context.Background(), event(chirpID)))
+	assert.Len(s.T(), forwarded, 1)
+	assert.Equal(s.T(), chirpID, forwarded[0].ChirpID)
+	assert.NotZero(s.T(), forwarded[0].ID)
+}
+
+func TestValidateBroker(t *testing.T) {
+	suite.Run(t, new(ValidateBroker))
+}
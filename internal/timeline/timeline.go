@@ -0,0 +1,154 @@
+package timeline
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	// DefaultMaxEntries is how many chirps a precomputed timeline is
+	// backfilled with, older pages are not reachable once it is enabled.
+	DefaultMaxEntries = 1000
+	// DefaultPrecomputeThreshold is the number of followed accounts above
+	// which reading the timeline with a single query gets too expensive.
+	DefaultPrecomputeThreshold = 500
+)
+
+// Cursor points at the last chirp of a page, the next page starts right
+// after it.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "_" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseCursor(cursor string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	nanos, id, found := strings.Cut(string(raw), "_")
+	if !found {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	return Cursor{CreatedAt: time.Unix(0, unixNano).UTC(), ID: parsed}, nil
+}
+
+// Timeline pages through a user's home timeline: chirps from the accounts
+// they follow and their own, newest first. A nil cursor starts at the top.
+type Timeline interface {
+	Page(ctx context.Context, userID uuid.UUID, cursor *Cursor, pageSize int32) ([]database.Chirp, error)
+}
+
+// FanOutOnRead builds the timeline with a single query over chirps and
+// follows on every request. It needs no upkeep but gets slower the more
+// accounts a user follows.
+type FanOutOnRead struct {
+	Queries *database.Queries
+}
+
+func (f FanOutOnRead) Page(ctx context.Context, userID uuid.UUID, cursor *Cursor, pageSize int32) ([]database.Chirp, error) {
+	params := database.RetrieveTimelineFanOutParams{
+		UserID:   userID,
+		PageSize: pageSize,
+	}
+	if cursor != nil {
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	return f.Queries.RetrieveTimelineFanOut(ctx, params)
+}
+
+// Precomputed reads the timeline from timeline_entries, which is written to
+// whenever an account the user follows chirps. Only users with
+// precomputed_timeline set get entries.
+type Precomputed struct {
+	Queries    *database.Queries
+	MaxEntries int32
+}
+
+func (p Precomputed) Page(ctx context.Context, userID uuid.UUID, cursor *Cursor, pageSize int32) ([]database.Chirp, error) {
+	params := database.RetrieveTimelinePrecomputedParams{
+		UserID:   userID,
+		PageSize: pageSize,
+	}
+	if cursor != nil {
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	return p.Queries.RetrieveTimelinePrecomputed(ctx, params)
+}
+
+// Enable switches a user over to a precomputed timeline and backfills it
+// with their most recent MaxEntries chirps.
+func (p Precomputed) Enable(ctx context.Context, userID uuid.UUID) error {
+	err := p.Queries.SetPrecomputedTimeline(ctx, database.SetPrecomputedTimelineParams{
+		ID:                  userID,
+		PrecomputedTimeline: true,
+	})
+	if err != nil {
+		return err
+	}
+	return p.Queries.BackfillTimeline(ctx, database.BackfillTimelineParams{
+		UserID:     userID,
+		MaxEntries: p.MaxEntries,
+	})
+}
+
+// Publish writes a new chirp into the timelines of its author and followers
+// that are precomputed.
+func (p Precomputed) Publish(ctx context.Context, chirp database.Chirp) error {
+	return p.Queries.FanOutChirpToTimelines(ctx, database.FanOutChirpToTimelinesParams{
+		ChirpID:   chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UserID:    chirp.UserID,
+	})
+}
+
+// Follow backfills a precomputed timeline with the recent chirps of a newly
+// followed account.
+func (p Precomputed) Follow(ctx context.Context, userID, authorID uuid.UUID) error {
+	return p.Queries.BackfillTimelineFromAuthor(ctx, database.BackfillTimelineFromAuthorParams{
+		UserID:     userID,
+		AuthorID:   authorID,
+		MaxEntries: p.MaxEntries,
+	})
+}
+
+// Unfollow removes an account's chirps from a precomputed timeline.
+func (p Precomputed) Unfollow(ctx context.Context, userID, authorID uuid.UUID) error {
+	return p.Queries.DeleteTimelineEntriesFromAuthor(ctx, database.DeleteTimelineEntriesFromAuthorParams{
+		UserID:   userID,
+		AuthorID: authorID,
+	})
+}
+
+// For picks the strategy a user's timeline is read with.
+func For(user database.User, fanOut FanOutOnRead, precomputed Precomputed) Timeline {
+	if user.PrecomputedTimeline {
+		return precomputed
+	}
+	return fanOut
+}
@@ -0,0 +1,131 @@
+package timeline
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type ValidateCursor struct {
+	suite.Suite
+}
+
+func (s *ValidateCursor) TestRoundTrip() {
+	cursor := Cursor{
+		CreatedAt: time.Date(2024, 10, 28, 13, 37, 0, 123456789, time.UTC),
+		ID:        uuid.New(),
+	}
+	parsed, err := ParseCursor(cursor.String())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), cursor, parsed)
+}
+
+func (s *ValidateCursor) TestInvalidCursor() {
+	for _, cursor := range []string{"", "not base64!", "bm8tc2VwYXJhdG9y", "YWJjX25vdC1hLXV1aWQ"} {
+		_, err := ParseCursor(cursor)
+		assert.Error(s.T(), err, cursor)
+	}
+}
+
+func TestValidateCursor(t *testing.T) {
+	suite.Run(t, new(ValidateCursor))
+}
+
+const (
+	benchmarkAuthors         = 200
+	benchmarkChirpsPerAuthor = 25
+)
+
+// setupBenchmark seeds a reader following benchmarkAuthors accounts with a
+// precomputed timeline. It needs a migrated database in DB_URL and removes
+// everything it created when the benchmark ends.
+func setupBenchmark(b *testing.B) (*database.Queries, uuid.UUID) {
+	dbUrl := os.Getenv("DB_URL")
+	if dbUrl == "" {
+		b.Skip("DB_URL is not set")
+	}
+	db, err := sql.Open("postgres", dbUrl)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+	queries := database.New(db)
+	var created []uuid.UUID
+	b.Cleanup(func() {
+		for _, id := range created {
+			_ = queries.DeleteUserById(ctx, id)
+		}
+		db.Close()
+	})
+	newUser := func() uuid.UUID {
+		user, err := queries.CreateUser(ctx, database.CreateUserParams{
+			Email:          fmt.Sprintf("timeline-bench-%s@example.com", uuid.NewString()),
+			HashedPassword: "-",
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		created = append(created, user.ID)
+		return user.ID
+	}
+	reader := newUser()
+	for i := 0; i < benchmarkAuthors; i++ {
+		author := newUser()
+		_, err := queries.CreateFollow(ctx, database.CreateFollowParams{
+			FollowerID: reader,
+			FolloweeID: author,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < benchmarkChirpsPerAuthor; j++ {
+			_, err := queries.CreateChirp(ctx, database.CreateChirpParams{
+				Body:   fmt.Sprintf("chirp %d from author %d", j, i),
+				UserID: author,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	err = Precomputed{Queries: queries, MaxEntries: DefaultMaxEntries}.Enable(ctx, reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return queries, reader
+}
+
+func benchmarkTimeline(b *testing.B, timeline Timeline, reader uuid.UUID) {
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// first page plus a page deep into the timeline
+		chirps, err := timeline.Page(ctx, reader, nil, DefaultPageSize)
+		if err != nil {
+			b.Fatal(err)
+		}
+		last := chirps[len(chirps)-1]
+		_, err = timeline.Page(ctx, reader, &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, DefaultPageSize)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFanOutOnRead(b *testing.B) {
+	queries, reader := setupBenchmark(b)
+	benchmarkTimeline(b, FanOutOnRead{Queries: queries}, reader)
+}
+
+func BenchmarkPrecomputed(b *testing.B) {
+	queries, reader := setupBenchmark(b)
+	benchmarkTimeline(b, Precomputed{Queries: queries, MaxEntries: DefaultMaxEntries}, reader)
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"chirpy/internal/database"
+	"chirpy/internal/storage"
 	"net/http"
 	"sync/atomic"
 )
@@ -11,6 +12,7 @@ type ApiConfig struct {
 	DbQueries      *database.Queries
 	JwtSecret      []byte
 	PolkaKey       string
+	Storage        storage.Storage
 }
 
 func (config *ApiConfig) MiddlewareMetricsInc(next http.Handler) http.Handler {
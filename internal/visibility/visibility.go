@@ -0,0 +1,44 @@
+package visibility
+
+import "errors"
+
+const (
+	// Public chirps are listed for everyone.
+	Public = "public"
+	// FollowersOnly chirps are only seen by their author's followers.
+	FollowersOnly = "followers_only"
+	// Unlisted chirps are seen by anyone with their id but left out of
+	// GET /api/chirps and tag pages.
+	Unlisted = "unlisted"
+)
+
+var ErrInvalid = errors.New("visibility must be public, followers_only or unlisted")
+
+// Parse checks a visibility sent by a client, a chirp without one is public.
+func Parse(visibility string) (string, error) {
+	switch visibility {
+	case "":
+		return Public, nil
+	case Public, FollowersOnly, Unlisted:
+		return visibility, nil
+	}
+	return "", ErrInvalid
+}
+
+// Reachable reports whether a chirp can be opened by a viewer who may be its
+// author or one of their followers.
+func Reachable(visibility string, isAuthor, isFollower bool) bool {
+	return visibility != FollowersOnly || isAuthor || isFollower
+}
+
+// Listed reports whether a chirp belongs in a viewer's listings: public
+// chirps, their own, and followers-only ones from authors they follow.
+func Listed(visibility string, isAuthor, isFollower bool) bool {
+	return visibility == Public || isAuthor || (visibility == FollowersOnly && isFollower)
+}
+
+// Shareable reports whether a chirp can be rechirped or quoted by someone
+// other than its author, which would show it beyond its audience.
+func Shareable(visibility string) bool {
+	return visibility != FollowersOnly
+}
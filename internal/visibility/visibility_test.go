@@ -0,0 +1,53 @@
+package visibility
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ValidateVisibility struct {
+	suite.Suite
+}
+
+func (s *ValidateVisibility) TestParse() {
+	for _, visibility := range []string{Public, FollowersOnly, Unlisted} {
+		parsed, err := Parse(visibility)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), visibility, parsed)
+	}
+	parsed, err := Parse("")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), Public, parsed)
+	_, err = Parse("private")
+	assert.ErrorIs(s.T(), err, ErrInvalid)
+	_, err = Parse("Public")
+	assert.ErrorIs(s.T(), err, ErrInvalid)
+}
+
+func (s *ValidateVisibility) TestReachable() {
+	assert.True(s.T(), Reachable(Public, false, false))
+	assert.True(s.T(), Reachable(Unlisted, false, false))
+	assert.False(s.T(), Reachable(FollowersOnly, false, false))
+	assert.True(s.T(), Reachable(FollowersOnly, false, true))
+	assert.True(s.T(), Reachable(FollowersOnly, true, false))
+}
+
+func (s *ValidateVisibility) TestListed() {
+	assert.True(s.T(), Listed(Public, false, false))
+	assert.False(s.T(), Listed(Unlisted, false, true))
+	assert.True(s.T(), Listed(Unlisted, true, false))
+	assert.False(s.T(), Listed(FollowersOnly, false, false))
+	assert.True(s.T(), Listed(FollowersOnly, false, true))
+	assert.True(s.T(), Listed(FollowersOnly, true, false))
+}
+
+func (s *ValidateVisibility) TestShareable() {
+	assert.True(s.T(), Shareable(Public))
+	assert.True(s.T(), Shareable(Unlisted))
+	assert.False(s.T(), Shareable(FollowersOnly))
+}
+
+func TestValidateVisibility(t *testing.T) {
+	suite.Run(t, new(ValidateVisibility))
+}
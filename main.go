@@ -1,22 +1,53 @@
 package main
 
 import (
+	"bytes"
 	"chirpy/internal/auth"
+	"chirpy/internal/bookmarks"
 	"chirpy/internal/database"
+	"chirpy/internal/digest"
+	"chirpy/internal/drafts"
+	"chirpy/internal/handles"
+	"chirpy/internal/hashtags"
+	"chirpy/internal/media"
+	"chirpy/internal/mentions"
+	"chirpy/internal/messages"
+	"chirpy/internal/moderation"
+	"chirpy/internal/mutedwords"
+	"chirpy/internal/notifications"
+	"chirpy/internal/pins"
+	"chirpy/internal/polls"
+	"chirpy/internal/realtime"
+	"chirpy/internal/reports"
+	"chirpy/internal/scheduler"
+	"chirpy/internal/settings"
+	"chirpy/internal/storage"
+	"chirpy/internal/stream"
+	"chirpy/internal/timeline"
 	"chirpy/internal/utils"
+	"chirpy/internal/visibility"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/mail"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 func main() {
@@ -29,18 +60,252 @@ func main() {
 	platform := os.Getenv("PLATFORM")
 	jwtSecret := os.Getenv("JWT_SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
+	precomputeThreshold, err := strconv.ParseInt(os.Getenv("TIMELINE_PRECOMPUTE_THRESHOLD"), 10, 64)
+	if err != nil {
+		precomputeThreshold = timeline.DefaultPrecomputeThreshold
+	}
+	maxPinnedChirps, err := strconv.ParseInt(os.Getenv("MAX_PINNED_CHIRPS"), 10, 32)
+	if err != nil || maxPinnedChirps < 0 {
+		maxPinnedChirps = pins.DefaultMax
+	}
+	maxChirpStreams, err := strconv.ParseInt(os.Getenv("MAX_CHIRP_STREAMS"), 10, 32)
+	if err != nil || maxChirpStreams < 1 {
+		maxChirpStreams = stream.DefaultMaxStreams
+	}
 
 	db, err := sql.Open("postgres", dbUrl)
 	if err != nil {
 		_ = fmt.Errorf("error opening database connection: %v", err)
 	}
 
+	var store storage.Storage = storage.Local{Dir: "./media", BaseURL: "/app/media"}
+	if os.Getenv("STORAGE_BACKEND") == "s3" {
+		store = storage.S3{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		}
+	}
+
+	// digest emails are only logged unless MAILER=smtp
+	var mailer digest.Mailer = digest.Log{}
+	if os.Getenv("MAILER") == "smtp" {
+		mailer = digest.SMTP{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
 	serveMux := http.NewServeMux()
 	config := utils.ApiConfig{
 		FileServerHits: atomic.Int32{},
 		DbQueries:      database.New(db),
 		JwtSecret:      []byte(jwtSecret),
 		PolkaKey:       polkaKey,
+		Storage:        store,
+	}
+	fanOutTimeline := timeline.FanOutOnRead{Queries: config.DbQueries}
+	// chirp streams are fed in-process, or through Postgres with
+	// CHIRP_STREAM_FANOUT=postgres so that every instance sees every chirp
+	chirpBroker := stream.NewBroker(stream.DefaultReplaySize, int(maxChirpStreams))
+	var chirpStream stream.Fanout = chirpBroker
+	chirpStreamListener := stream.Postgres{
+		DB:      db,
+		DSN:     dbUrl,
+		Channel: stream.DefaultChannel,
+		Broker:  chirpBroker,
+	}
+	streamThroughPostgres := os.Getenv("CHIRP_STREAM_FANOUT") == "postgres"
+	if streamThroughPostgres {
+		chirpStream = chirpStreamListener
+	}
+	// sockets get chirps from the broker whichever way it's fed, and
+	// notifications as they're committed, from Postgres
+	socketHub := realtime.NewHub(config.DbQueries)
+	chirpBroker.Forward(socketHub.DispatchChirp)
+	precomputedTimeline := timeline.Precomputed{
+		Queries:    config.DbQueries,
+		MaxEntries: timeline.DefaultMaxEntries,
+	}
+	trendingRefresher := hashtags.Refresher{
+		DB:       db,
+		Queries:  config.DbQueries,
+		Window:   hashtags.DefaultWindow,
+		HalfLife: hashtags.DefaultHalfLife,
+		Interval: hashtags.DefaultInterval,
+		Limit:    hashtags.DefaultLimit,
+	}
+	chirpScheduler := scheduler.Publisher{
+		DB:      db,
+		Queries: config.DbQueries,
+		OnPublish: func(ctx context.Context, queries *database.Queries, chirp database.Chirp) error {
+			published := timeline.Precomputed{Queries: queries, MaxEntries: precomputedTimeline.MaxEntries}
+			if err := published.Publish(ctx, chirp); err != nil {
+				return err
+			}
+			if err := storeChirpTags(ctx, queries, chirp); err != nil {
+				return err
+			}
+			if err := storeChirpMentions(ctx, queries, chirp); err != nil {
+				return err
+			}
+			return notifyQuoted(ctx, queries, chirp)
+		},
+		Published: func(ctx context.Context, chirp database.Chirp) {
+			streamCreatedChirp(ctx, &config, chirpStream, chirp)
+		},
+		Interval: scheduler.DefaultInterval,
+	}
+	digestSender := digest.Sender{
+		DB:       db,
+		Queries:  config.DbQueries,
+		Mailer:   mailer,
+		Secret:   config.JwtSecret,
+		BaseURL:  baseURL,
+		Interval: digest.DefaultInterval,
+	}
+	mediaWorker := media.NewWorker(config.DbQueries, store)
+	// rules come from MODERATION_RULES_FILE when it's set, they can't be
+	// changed through the admin endpoints then
+	moderationRulesFile := os.Getenv("MODERATION_RULES_FILE")
+	moderationFilter := moderation.NewFilter(nil)
+	moderationReloader := moderation.Reloader{
+		Filter:   moderationFilter,
+		Queries:  config.DbQueries,
+		Interval: moderation.DefaultReloadInterval,
+	}
+	if moderationRulesFile != "" {
+		rules, err := moderation.LoadFile(moderationRulesFile)
+		if err != nil {
+			log.Fatalf("error loading moderation rules: %v", err)
+		}
+		moderationFilter.SetRules(rules)
+	} else if err := moderationReloader.Reload(context.Background()); err != nil {
+		// serving with no rules would let every chirp through
+		log.Fatalf("error loading moderation rules: %v", err)
+	}
+	// postChirp stores a chirp checked the way every new chirp is, whether
+	// it's sent to POST /api/chirps or published from a draft. Callers run
+	// it in a transaction through queries, so a chirp is stored with its
+	// poll, tags and mentions or not at all. Errors the author can fix are
+	// a *requestError.
+	postChirp := func(ctx context.Context, queries *database.Queries, userID uuid.UUID, params newChirp) (database.Chirp, error) {
+		if len(params.Body) > 140 {
+			return database.Chirp{}, &requestError{Status: http.StatusBadRequest, Message: "Chirp is too long"}
+		}
+		moderated := moderationFilter.Check(params.Body)
+		if moderated.Rejected() {
+			return database.Chirp{}, &requestError{Status: http.StatusBadRequest, Message: "Chirp contains prohibited content"}
+		}
+		// a chirp sent without a visibility gets the author's default
+		if params.Visibility == "" {
+			authorSettings, err := settings.Load(ctx, queries, userID)
+			if err != nil {
+				return database.Chirp{}, fmt.Errorf("getting settings: %w", err)
+			}
+			params.Visibility = authorSettings.DefaultVisibility
+		}
+		chirpVisibility, err := visibility.Parse(params.Visibility)
+		if err != nil {
+			return database.Chirp{}, &requestError{Status: http.StatusBadRequest, Message: err.Error()}
+		}
+		publishAt := sql.NullTime{}
+		if params.PublishAt != nil {
+			author, err := queries.GetUserById(ctx, userID)
+			if err != nil {
+				return database.Chirp{}, &requestError{Status: http.StatusNotFound, Message: "user not found"}
+			}
+			if !author.IsChirpyRed {
+				return database.Chirp{}, &requestError{Status: http.StatusForbidden, Message: scheduler.ErrNotRed.Error()}
+			}
+			if err := scheduler.Validate(*params.PublishAt, time.Now()); err != nil {
+				return database.Chirp{}, &requestError{Status: http.StatusBadRequest, Message: err.Error()}
+			}
+			publishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+		}
+		if params.Poll != nil {
+			// the poll opens when the chirp is published
+			opensAt := time.Now()
+			if params.PublishAt != nil {
+				opensAt = *params.PublishAt
+			}
+			if err := polls.Validate(params.Poll.Options, opensAt, params.Poll.ClosesAt); err != nil {
+				return database.Chirp{}, &requestError{Status: http.StatusBadRequest, Message: err.Error()}
+			}
+		}
+		quotedChirpID := uuid.NullUUID{}
+		if params.QuotedChirpID != nil {
+			if strings.TrimSpace(params.Body) == "" {
+				return database.Chirp{}, &requestError{Status: http.StatusBadRequest, Message: "quote chirp needs a body, use rechirp instead"}
+			}
+			quoted, err := queries.RetrieveChirpById(ctx, *params.QuotedChirpID)
+			if err != nil || !chirpVisibleTo(ctx, queries, quoted, uuid.NullUUID{UUID: userID, Valid: true}) {
+				return database.Chirp{}, &requestError{Status: http.StatusNotFound, Message: "quoted chirp not found"}
+			}
+			if quoted.UserID != userID && !visibility.Shareable(quoted.Visibility) {
+				return database.Chirp{}, &requestError{Status: http.StatusForbidden, Message: "followers-only chirps can't be quoted"}
+			}
+			if quoted.IsRechirp {
+				quotedChirpID = quoted.QuotedChirpID
+			} else {
+				quotedChirpID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+			}
+		}
+		var chirp database.Chirp
+		if publishAt.Valid {
+			chirp, err = queries.CreateScheduledChirp(ctx, database.CreateScheduledChirpParams{
+				Body:          moderated.Body,
+				UserID:        userID,
+				QuotedChirpID: quotedChirpID,
+				PublishAt:     publishAt,
+				Visibility:    chirpVisibility,
+			})
+		} else {
+			chirp, err = queries.CreateChirp(ctx, database.CreateChirpParams{
+				Body:          moderated.Body,
+				UserID:        userID,
+				QuotedChirpID: quotedChirpID,
+				Visibility:    chirpVisibility,
+			})
+		}
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		if params.Poll != nil {
+			if err := storeChirpPoll(ctx, queries, chirp, *params.Poll); err != nil {
+				return database.Chirp{}, fmt.Errorf("storing poll: %w", err)
+			}
+		}
+		// a scheduled chirp reaches timelines, tags and mentions when
+		// chirpScheduler publishes it
+		if !chirp.PublishAt.Valid {
+			published := timeline.Precomputed{Queries: queries, MaxEntries: precomputedTimeline.MaxEntries}
+			if err := published.Publish(ctx, chirp); err != nil {
+				return database.Chirp{}, fmt.Errorf("publishing to timelines: %w", err)
+			}
+			if err := storeChirpTags(ctx, queries, chirp); err != nil {
+				return database.Chirp{}, fmt.Errorf("storing tags: %w", err)
+			}
+			if err := storeChirpMentions(ctx, queries, chirp); err != nil {
+				return database.Chirp{}, fmt.Errorf("storing mentions: %w", err)
+			}
+			if err := notifyQuoted(ctx, queries, chirp); err != nil {
+				return database.Chirp{}, fmt.Errorf("notifying quoted author: %w", err)
+			}
+		}
+		if err := storeChirpFlags(ctx, queries, chirp, moderated); err != nil {
+			return database.Chirp{}, fmt.Errorf("storing flags: %w", err)
+		}
+		return chirp, nil
 	}
 	var server = &http.Server{
 		Addr:    ":8080",
@@ -62,6 +327,47 @@ func main() {
 			),
 		),
 	)
+	// only processed images are served, uploads waiting in ./media/incoming
+	// still carry their EXIF data. Directories aren't listed, they'd give
+	// away the ids of chirps with images, and an image is only served to
+	// whoever can see its chirp.
+	mediaFiles := http.StripPrefix("/app/media/chirps",
+		http.FileServer(storage.Files{Dir: http.Dir("./media/chirps/")}),
+	)
+	go serveMux.HandleFunc(
+		"/app/media/chirps/{chirpID}/{file}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "HEAD" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			chirp, err := config.DbQueries.RetrieveChirpOrScheduledById(r.Context(), chirpID)
+			if errors.Is(err, sql.ErrNoRows) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				log.Printf("error getting chirp %s for its media: %v", chirpID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			viewerID := optionalViewer(r, &config)
+			// scheduled chirps are only seen by their author
+			if (chirp.PublishAt.Valid && viewerID.UUID != chirp.UserID) || !chirpVisibleTo(r.Context(), config.DbQueries, chirp, viewerID) {
+				http.NotFound(w, r)
+				return
+			}
+			if chirp.Visibility != visibility.Public || chirp.PublishAt.Valid {
+				w.Header().Set("Cache-Control", "private")
+			}
+			mediaFiles.ServeHTTP(w, r)
+		},
+	)
 	go serveMux.HandleFunc(
 		"/api/healthz",
 		func(w http.ResponseWriter, r *http.Request) {
@@ -113,715 +419,7124 @@ func main() {
 		},
 	)
 	go serveMux.HandleFunc(
-		"/api/chirps",
+		"/admin/moderation/rules",
 		func(w http.ResponseWriter, r *http.Request) {
-			if r.Method == "POST" {
-				type parameters struct {
-					Body   string    `json:"body"`
-					UserID uuid.UUID `json:"user_id"`
-				}
-
-				type response struct {
-					ID        uuid.UUID `json:"id"`
-					CreatedAt time.Time `json:"created_at"`
-					UpdatedAt time.Time `json:"updated_at"`
-					Body      string    `json:"body"`
-					UserID    uuid.UUID `json:"user_id"`
-				}
-				decoder := json.NewDecoder(r.Body)
-				params := parameters{}
-				err := decoder.Decode(&params)
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if _, ok := authenticateModerator(w, r, &config); !ok {
+				return
+			}
+			if moderationRulesFile != "" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "moderation rules are loaded from a file",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "GET" {
+				rules, err := config.DbQueries.GetModerationRules(r.Context())
 				if err != nil {
-					dat, err := json.Marshal(utils.Error{
-						Error: "error marshalling JSON: " + err.Error(),
-					})
-					if err != nil {
-						log.Printf("error writing /api/chirps response: %v", err)
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-					w.WriteHeader(http.StatusBadRequest)
-					w.Write(dat)
-					return
-				}
-
-				if len(params.Body) > 140 {
-					dat, err := json.Marshal(utils.Message{
-						Message: "Chirp is too long",
-					})
-					if err != nil {
-						log.Printf("error writing /validate_chirp response: %v", err)
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-					w.WriteHeader(http.StatusBadRequest)
-					w.Write(dat)
-					return
-				} else {
-					w.Header().Add("Content-Type", "application/json")
-					body := strings.Split(params.Body, " ")
-					strings.ReplaceAll(body[0], " ", "")
-					for i, word := range body {
-						if strings.ToLower(word) == "kerfuffle" {
-							body[i] = "****"
-						}
-						if strings.ToLower(word) == "sharbert" {
-							body[i] = "****"
-						}
-						if strings.ToLower(word) == "fornax" {
-							body[i] = "****"
-						}
-					}
-					bearerToken, bearerTokenErr := auth.GetBearerToken(r.Header)
-					if bearerTokenErr != nil {
-						w.WriteHeader(http.StatusBadRequest)
-						return
-					}
-					userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
-					if err != nil {
-						marshal, _ := json.Marshal(utils.Error{
-							Error: err.Error(),
-						})
-						w.WriteHeader(http.StatusUnauthorized)
-						w.Write(marshal)
-						return
-					}
-					chirp, err := config.DbQueries.CreateChirp(r.Context(), database.CreateChirpParams{
-						Body:   strings.Join(body, " "),
-						UserID: userID,
-					})
-					if err != nil {
-						return
-					}
-					dat, err := json.Marshal(response{
-						ID:        chirp.ID,
-						CreatedAt: chirp.CreatedAt,
-						UpdatedAt: chirp.UpdatedAt,
-						Body:      chirp.Body,
-						UserID:    chirp.UserID,
-					})
-					if err != nil {
-						log.Printf("error writing /validate_chirp response: %v", err)
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-					w.WriteHeader(http.StatusCreated)
-					w.Write(dat)
+					log.Printf("error retrieving moderation rules: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
 					return
-
 				}
-			} else if r.Method == "GET" {
-				author := r.URL.Query().Get("author_id")
-				sortAsc := r.URL.Query().Get("sort")
-				type response struct {
-					ID        uuid.UUID `json:"id"`
-					CreatedAt time.Time `json:"created_at"`
-					UpdatedAt time.Time `json:"updated_at"`
-					Body      string    `json:"body"`
-					UserID    uuid.UUID `json:"user_id"`
+				retRules := make([]moderationRuleResponse, len(rules))
+				for i, rule := range rules {
+					retRules[i] = moderationRuleResponseFrom(rule)
 				}
-				w.Header().Add("Content-Type", "application/json")
-				if sortAsc == "asc" {
-					if author != "" {
-						parsed, parseErr := uuid.Parse(author)
-						if parseErr != nil {
-							marshal, _ := json.Marshal(utils.Error{
-								Error: "invalid author",
-							})
-							w.WriteHeader(http.StatusBadRequest)
-							w.Write(marshal)
-							return
-						}
-						chirps, err := config.DbQueries.RetrieveChirpsByAuthor(r.Context(), parsed)
-						if err != nil {
-							marshal, _ := json.Marshal(utils.Error{
-								Error: "chirps by given author is not found",
-							})
-							w.WriteHeader(http.StatusNotFound)
-							w.Write(marshal)
-							return
-						}
-						retChirps := make([]response, len(chirps))
-						for i, chirp := range chirps {
-							retChirps[i] = response{
-								ID:        chirp.ID,
-								CreatedAt: chirp.CreatedAt,
-								UpdatedAt: chirp.UpdatedAt,
-								Body:      chirp.Body,
-								UserID:    chirp.UserID,
-							}
-						}
-						dat, err := json.Marshal(retChirps)
-						w.Write(dat)
-						return
-					} else {
-						chirps, err := config.DbQueries.RetrieveChirps(r.Context())
-						if err != nil {
-							return
-						}
-						retChirps := make([]response, len(chirps))
-						for i, chirp := range chirps {
-							retChirps[i] = response{
-								ID:        chirp.ID,
-								CreatedAt: chirp.CreatedAt,
-								UpdatedAt: chirp.UpdatedAt,
-								Body:      chirp.Body,
-								UserID:    chirp.UserID,
-							}
-						}
-						dat, err := json.Marshal(retChirps)
-						w.Write(dat)
-					}
-				} else if sortAsc == "desc" {
-					if author != "" {
-						parsed, parseErr := uuid.Parse(author)
-						if parseErr != nil {
-							marshal, _ := json.Marshal(utils.Error{
-								Error: "invalid author",
-							})
-							w.WriteHeader(http.StatusBadRequest)
-							w.Write(marshal)
-							return
-						}
-						chirps, err := config.DbQueries.RetrieveChirpsByAuthorDesc(r.Context(), parsed)
-						if err != nil {
-							marshal, _ := json.Marshal(utils.Error{
-								Error: "chirps by given author is not found",
-							})
-							w.WriteHeader(http.StatusNotFound)
-							w.Write(marshal)
-							return
-						}
-						retChirps := make([]response, len(chirps))
-						for i, chirp := range chirps {
-							retChirps[i] = response{
-								ID:        chirp.ID,
-								CreatedAt: chirp.CreatedAt,
-								UpdatedAt: chirp.UpdatedAt,
-								Body:      chirp.Body,
-								UserID:    chirp.UserID,
-							}
-						}
-						dat, err := json.Marshal(retChirps)
-						w.Write(dat)
-						return
-					} else {
-						chirps, err := config.DbQueries.RetrieveChirpsDesc(r.Context())
-						if err != nil {
-							return
-						}
-						retChirps := make([]response, len(chirps))
-						for i, chirp := range chirps {
-							retChirps[i] = response{
-								ID:        chirp.ID,
-								CreatedAt: chirp.CreatedAt,
-								UpdatedAt: chirp.UpdatedAt,
-								Body:      chirp.Body,
-								UserID:    chirp.UserID,
-							}
-						}
-						dat, err := json.Marshal(retChirps)
-						w.Write(dat)
-					}
+				dat, err := json.Marshal(retRules)
+				if err != nil {
+					log.Printf("error writing /admin/moderation/rules response: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
 				}
+				w.WriteHeader(http.StatusOK)
+				w.Write(dat)
+				return
+			}
+			type parameters struct {
+				Term   string `json:"term"`
+				Action string `json:"action"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			params.Term = strings.TrimSpace(params.Term)
+			rule := moderation.Rule{Term: params.Term, Action: moderation.Action(params.Action)}
+			if err := rule.Validate(); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			created, err := config.DbQueries.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+				Term:   params.Term,
+				Action: params.Action,
+			})
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "a rule for this term already exists",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error creating moderation rule: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := moderationReloader.Reload(r.Context()); err != nil {
+				log.Printf("error reloading moderation rules: %v", err)
+			}
+			dat, err := json.Marshal(moderationRuleResponseFrom(created))
+			if err != nil {
+				log.Printf("error writing /admin/moderation/rules response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
 			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
 		},
 	)
 	go serveMux.HandleFunc(
-		"/api/chirps/{id}",
+		"/admin/moderation/rules/{id}",
 		func(w http.ResponseWriter, r *http.Request) {
-			if r.Method == "GET" {
-				type response struct {
-					ID        uuid.UUID `json:"id"`
-					CreatedAt time.Time `json:"created_at"`
-					UpdatedAt time.Time `json:"updated_at"`
-					Body      string    `json:"body"`
-					UserID    uuid.UUID `json:"user_id"`
-				}
-				id := r.PathValue("id")
-				if id != "" {
-					chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), uuid.MustParse(id))
-					if err != nil {
-						w.WriteHeader(http.StatusNotFound)
-						return
-					}
-
-					if chirp.Body == "" {
-						w.WriteHeader(http.StatusNotFound)
-						return
-					}
-
-					dat, err := json.Marshal(response{
-						ID:        chirp.ID,
-						CreatedAt: chirp.CreatedAt,
-						UpdatedAt: chirp.UpdatedAt,
-						Body:      chirp.Body,
-						UserID:    chirp.UserID,
-					})
-					if err != nil {
-						log.Printf("error writing /validate_chirp response: %v", err)
-						w.WriteHeader(http.StatusInternalServerError)
-						return
-					}
-					w.Write(dat)
+			if r.Method != "PUT" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if _, ok := authenticateModerator(w, r, &config); !ok {
+				return
+			}
+			if moderationRulesFile != "" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "moderation rules are loaded from a file",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			ruleID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid rule id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				rows, err := config.DbQueries.DeleteModerationRule(r.Context(), ruleID)
+				if err != nil {
+					log.Printf("error deleting moderation rule %s: %v", ruleID, err)
+					w.WriteHeader(http.StatusInternalServerError)
 					return
 				}
-			} else if r.Method == "DELETE" {
-				id := r.PathValue("id")
-				bearerToken, err := auth.GetBearerToken(r.Header)
-				if err != nil {
-					marshal, _ := json.Marshal(utils.Error{
-						Error: "invalid authentication token",
-					})
-					w.WriteHeader(http.StatusUnauthorized)
-					w.Write(marshal)
+				if rows == 0 {
+					w.WriteHeader(http.StatusNotFound)
 					return
 				}
-				userID, validateJWTErr := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
-				if validateJWTErr != nil {
+				if err := moderationReloader.Reload(r.Context()); err != nil {
+					log.Printf("error reloading moderation rules: %v", err)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			existing, err := config.DbQueries.GetModerationRuleById(r.Context(), ruleID)
+			if errors.Is(err, sql.ErrNoRows) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("error retrieving moderation rule %s: %v", ruleID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			// fields left out keep their current value
+			type parameters struct {
+				Term   *string `json:"term"`
+				Action *string `json:"action"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			update := database.UpdateModerationRuleParams{
+				ID:     existing.ID,
+				Term:   existing.Term,
+				Action: existing.Action,
+			}
+			if params.Term != nil {
+				update.Term = strings.TrimSpace(*params.Term)
+			}
+			if params.Action != nil {
+				update.Action = *params.Action
+			}
+			rule := moderation.Rule{Term: update.Term, Action: moderation.Action(update.Action)}
+			if err := rule.Validate(); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			updated, err := config.DbQueries.UpdateModerationRule(r.Context(), update)
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "a rule for this term already exists",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error updating moderation rule %s: %v", ruleID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := moderationReloader.Reload(r.Context()); err != nil {
+				log.Printf("error reloading moderation rules: %v", err)
+			}
+			dat, err := json.Marshal(moderationRuleResponseFrom(updated))
+			if err != nil {
+				log.Printf("error writing /admin/moderation/rules response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/admin/moderation/flags",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Flags      []chirpFlagResponse `json:"flags"`
+				NextCursor string              `json:"next_cursor,omitempty"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if _, ok := authenticateModerator(w, r, &config); !ok {
+				return
+			}
+			pageSize := int64(timeline.DefaultPageSize)
+			if limit := r.URL.Query().Get("limit"); limit != "" {
+				var err error
+				pageSize, err = strconv.ParseInt(limit, 10, 32)
+				if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
 					marshal, _ := json.Marshal(utils.Error{
-						Error: "invalid authentication token",
+						Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
 					})
-					w.WriteHeader(http.StatusForbidden)
+					w.WriteHeader(http.StatusBadRequest)
 					w.Write(marshal)
 					return
 				}
-				if id != "" {
-					chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), uuid.MustParse(id))
-					if err != nil {
-						marshal, _ := json.Marshal(utils.Error{
-							Error: "chirp not found",
-						})
-						w.WriteHeader(http.StatusNotFound)
-						w.Write(marshal)
-						return
-					}
-					if userID != chirp.UserID {
-						marshal, _ := json.Marshal(utils.Error{
-							Error: "unauthorized action",
-						})
-						w.WriteHeader(http.StatusForbidden)
-						w.Write(marshal)
-						return
-					}
-					deleteChirpErr := config.DbQueries.DeleteChirpById(r.Context(), chirp.ID)
-					if deleteChirpErr != nil {
-						marshal, _ := json.Marshal(utils.Error{
-							Error: "chirp not found",
-						})
-						w.WriteHeader(http.StatusNotFound)
-						w.Write(marshal)
-						return
-					}
-					w.WriteHeader(http.StatusNoContent)
-					return
-				} else {
+			}
+			params := database.RetrieveChirpFlagsParams{PageSize: int32(pageSize)}
+			if before := r.URL.Query().Get("cursor"); before != "" {
+				cursor, err := timeline.ParseCursor(before)
+				if err != nil {
 					marshal, _ := json.Marshal(utils.Error{
-						Error: "invalid chirp id",
+						Error: "invalid cursor",
 					})
 					w.WriteHeader(http.StatusBadRequest)
 					w.Write(marshal)
 					return
 				}
-			} else {
-				w.WriteHeader(http.StatusMethodNotAllowed)
+				params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+				params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+			}
+			flags, err := config.DbQueries.RetrieveChirpFlags(r.Context(), params)
+			if err != nil {
+				log.Printf("error retrieving chirp flags: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-
+			resp := response{Flags: make([]chirpFlagResponse, len(flags))}
+			for i, flag := range flags {
+				resp.Flags[i] = chirpFlagResponse{
+					ID:        flag.ID,
+					ChirpID:   flag.ChirpID,
+					Term:      flag.Term,
+					CreatedAt: flag.CreatedAt,
+				}
+				if flag.RuleID.Valid {
+					ruleID := flag.RuleID.UUID
+					resp.Flags[i].RuleID = &ruleID
+				}
+			}
+			if len(flags) == int(pageSize) {
+				last := flags[len(flags)-1]
+				resp.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+			}
+			dat, err := json.Marshal(resp)
+			if err != nil {
+				log.Printf("error writing /admin/moderation/flags response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
 		},
 	)
 	go serveMux.HandleFunc(
-		"/api/users",
+		"/api/reports",
 		func(w http.ResponseWriter, r *http.Request) {
-			type parameters struct {
-				Email    string `json:"email"`
-				Password string `json:"password"`
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
 			}
-			type response struct {
-				ID          uuid.UUID `json:"id"`
-				CreatedAt   time.Time `json:"created_at"`
-				UpdatedAt   time.Time `json:"updated_at"`
-				Email       string    `json:"email"`
-				IsChirpyRed bool      `json:"is_chirpy_red"`
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
 			}
-			if r.Method == "POST" {
-				w.Header().Add("Content-Type", "application/json")
-				decoder := json.NewDecoder(r.Body)
-				params := parameters{}
-				err := decoder.Decode(&params)
-				email, emailParseErr := mail.ParseAddress(params.Email)
-				if emailParseErr != nil {
-					w.WriteHeader(http.StatusBadRequest)
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+
+			// reporters follow what happened to their reports here, the
+			// outcome is set once a moderator resolves one
+			if r.Method == "GET" {
+				type response struct {
+					Reports    []reportResponse `json:"reports"`
+					NextCursor string           `json:"next_cursor,omitempty"`
 				}
-				hashed, err := auth.HashPassword(params.Password)
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					return
+				pageSize := int64(timeline.DefaultPageSize)
+				if limit := r.URL.Query().Get("limit"); limit != "" {
+					pageSize, err = strconv.ParseInt(limit, 10, 32)
+					if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
 				}
-				user, createUserErr := config.DbQueries.CreateUser(
-					r.Context(),
-					database.CreateUserParams{
-						Email:          email.Address,
-						HashedPassword: hashed,
-					},
-				)
-				if createUserErr != nil {
-					return
+				params := database.RetrieveReportsByReporterParams{
+					ReporterID: userID,
+					PageSize:   int32(pageSize),
 				}
-				if err != nil {
-					dat, err := json.Marshal(utils.Error{
-						Error: "error marshalling JSON: " + err.Error(),
-					})
+				if before := r.URL.Query().Get("cursor"); before != "" {
+					cursor, err := timeline.ParseCursor(before)
 					if err != nil {
-						log.Printf("error writing /api/users response: %v", err)
-						w.WriteHeader(http.StatusInternalServerError)
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "invalid cursor",
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
 						return
 					}
-					w.WriteHeader(http.StatusBadRequest)
-					w.Write(dat)
-					return
+					params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+					params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
 				}
-				dat, err := json.Marshal(response{
-					ID:          user.ID,
-					CreatedAt:   user.CreatedAt,
-					UpdatedAt:   user.UpdatedAt,
-					Email:       user.Email,
-					IsChirpyRed: user.IsChirpyRed,
-				})
+				found, err := config.DbQueries.RetrieveReportsByReporter(r.Context(), params)
 				if err != nil {
+					log.Printf("error retrieving reports of %s: %v", userID, err)
 					w.WriteHeader(http.StatusInternalServerError)
 					return
 				}
-				w.WriteHeader(http.StatusCreated)
-				w.Write(dat)
-			} else if r.Method == "PUT" {
-				type parameters struct {
-					Password string `json:"password"`
-					Email    string `json:"email"`
+				resp := response{Reports: make([]reportResponse, len(found))}
+				for i, report := range found {
+					// which moderator handled a report is none of the reporter's business
+					resp.Reports[i] = reportResponseFrom(report, false)
 				}
-				type response struct {
-					ID        uuid.UUID `json:"id"`
-					CreatedAt time.Time `json:"created_at"`
-					UpdatedAt time.Time `json:"updated_at"`
-					Email     string    `json:"email"`
+				if len(found) == int(pageSize) {
+					last := found[len(found)-1]
+					resp.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
 				}
-				bearerToken, err := auth.GetBearerToken(r.Header)
+				dat, err := json.Marshal(resp)
 				if err != nil {
-					marshal, _ := json.Marshal(utils.Error{
-						Error: "invalid authentication token",
-					})
-					w.WriteHeader(http.StatusUnauthorized)
-					w.Write(marshal)
+					log.Printf("error writing /api/reports response: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
 					return
 				}
-				userID, validateErr := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
-				if validateErr != nil {
+				w.WriteHeader(http.StatusOK)
+				w.Write(dat)
+				return
+			}
+
+			type parameters struct {
+				ChirpID *uuid.UUID `json:"chirp_id"`
+				UserID  *uuid.UUID `json:"user_id"`
+				Reason  string     `json:"reason"`
+				Details string     `json:"details"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			var validationErr error
+			switch {
+			case (params.ChirpID == nil) == (params.UserID == nil):
+				validationErr = reports.ErrNoTarget
+			case !reports.ValidReason(params.Reason):
+				validationErr = reports.ErrInvalidReason
+			case utf8.RuneCountInString(params.Details) > reports.MaxDetailsLength:
+				validationErr = reports.ErrDetailsTooLong
+			}
+			if validationErr != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: validationErr.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			report := database.CreateReportParams{
+				ReporterID: userID,
+				Reason:     params.Reason,
+				Details:    strings.TrimSpace(params.Details),
+			}
+			if params.ChirpID != nil {
+				chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), *params.ChirpID)
+				if err != nil {
 					marshal, _ := json.Marshal(utils.Error{
-						Error: "invalid authentication token",
+						Error: "chirp not found",
 					})
-					w.WriteHeader(http.StatusUnauthorized)
+					w.WriteHeader(http.StatusNotFound)
 					w.Write(marshal)
 					return
 				}
-				w.Header().Set("Content-Type", "application/json")
-				decoder := json.NewDecoder(r.Body)
-				params := parameters{}
-				decodeErr := decoder.Decode(&params)
-				if decodeErr != nil {
+				report.ChirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+				report.UserID = chirp.UserID
+			} else {
+				user, err := config.DbQueries.GetUserById(r.Context(), *params.UserID)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "user not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				report.UserID = user.ID
+			}
+			if report.UserID == userID {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "you cannot report yourself",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			created, err := config.DbQueries.CreateReport(r.Context(), report)
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "you already have an open report about this",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error creating report: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(reportResponseFrom(created, false))
+			if err != nil {
+				log.Printf("error writing /api/reports response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/admin/reports",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Reports    []reportResponse `json:"reports"`
+				NextCursor string           `json:"next_cursor,omitempty"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			moderator, ok := authenticateModerator(w, r, &config)
+			if !ok {
+				return
+			}
+			// the queue is oldest first, filtered by ?status= (open by
+			// default), ?reason=, ?target=chirp|user and ?assignee= which is
+			// a moderator id, "me" or "none"
+			query := r.URL.Query()
+			params := database.RetrieveReportQueueParams{Status: reports.StatusOpen}
+			if status := query.Get("status"); status != "" {
+				if status != reports.StatusOpen && status != reports.StatusResolved {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "status must be open or resolved",
+					})
 					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
 					return
 				}
-				email, emailParseErr := mail.ParseAddress(params.Email)
-				if emailParseErr != nil {
+				params.Status = status
+			}
+			if reason := query.Get("reason"); reason != "" {
+				if !reports.ValidReason(reason) {
 					marshal, _ := json.Marshal(utils.Error{
-						Error: "invalid email",
+						Error: reports.ErrInvalidReason.Error(),
 					})
 					w.WriteHeader(http.StatusBadRequest)
 					w.Write(marshal)
 					return
 				}
-				user, getUserErr := config.DbQueries.GetUserById(r.Context(), userID)
-				if getUserErr != nil {
-					log.Printf("error getting user by email %v: %v", params.Email, getUserErr)
+				params.Reason = sql.NullString{String: reason, Valid: true}
+			}
+			if target := query.Get("target"); target != "" {
+				if !reports.ValidTargetType(target) {
 					marshal, _ := json.Marshal(utils.Error{
-						Error: fmt.Sprintf("email %v not found", email.Address),
+						Error: "target must be chirp or user",
 					})
-					w.WriteHeader(http.StatusNotFound)
+					w.WriteHeader(http.StatusBadRequest)
 					w.Write(marshal)
 					return
 				}
-				hashedPassword, _ := auth.HashPassword(params.Password)
-				updatedUser, updateUserErr := config.DbQueries.UpdateUserByID(
-					r.Context(),
-					database.UpdateUserByIDParams{
-						ID:             userID,
-						Email:          email.Address,
-						CreatedAt:      user.CreatedAt,
-						HashedPassword: hashedPassword,
-					},
-				)
-				if updateUserErr != nil {
-					log.Printf("error updating user: %v", updateUserErr)
+				params.TargetType = sql.NullString{String: target, Valid: true}
+			}
+			switch assignee := query.Get("assignee"); assignee {
+			case "":
+			case "none":
+				params.Unassigned = true
+			case "me":
+				params.AssigneeID = uuid.NullUUID{UUID: moderator.ID, Valid: true}
+			default:
+				assigneeID, err := uuid.Parse(assignee)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "assignee must be a user id, me or none",
+					})
 					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
 					return
 				}
-				dat, err := json.Marshal(response{
-					ID:        updatedUser.ID,
-					CreatedAt: updatedUser.CreatedAt,
-					UpdatedAt: updatedUser.UpdatedAt,
-					Email:     updatedUser.Email,
-				})
+				params.AssigneeID = uuid.NullUUID{UUID: assigneeID, Valid: true}
+			}
+			pageSize := int64(timeline.DefaultPageSize)
+			if limit := query.Get("limit"); limit != "" {
+				var err error
+				pageSize, err = strconv.ParseInt(limit, 10, 32)
+				if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			}
+			params.PageSize = int32(pageSize)
+			if after := query.Get("cursor"); after != "" {
+				cursor, err := timeline.ParseCursor(after)
 				if err != nil {
-					log.Printf("error writing PUT /api/users response: %v", err)
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid cursor",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
 					return
 				}
-				w.Write(dat)
+				params.AfterCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+				params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+			}
+			queue, err := config.DbQueries.RetrieveReportQueue(r.Context(), params)
+			if err != nil {
+				log.Printf("error retrieving report queue: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			resp := response{Reports: make([]reportResponse, len(queue))}
+			for i, report := range queue {
+				resp.Reports[i] = reportResponseFrom(report, true)
+			}
+			if len(queue) == int(pageSize) {
+				last := queue[len(queue)-1]
+				resp.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+			}
+			dat, err := json.Marshal(resp)
+			if err != nil {
+				log.Printf("error writing /admin/reports response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
-			} else {
-				w.WriteHeader(http.StatusMethodNotAllowed)
 			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
 		},
 	)
-
 	go serveMux.HandleFunc(
-		"/api/login",
+		"/admin/reports/{id}/assign",
 		func(w http.ResponseWriter, r *http.Request) {
-			if r.Method != "POST" {
+			if r.Method != "POST" && r.Method != "DELETE" {
 				w.WriteHeader(http.StatusMethodNotAllowed)
 				return
 			}
-			type parameters struct {
-				Email    string `json:"email"`
-				Password string `json:"password"`
-			}
-			type response struct {
-				ID           uuid.UUID `json:"id"`
-				CreatedAt    time.Time `json:"created_at"`
-				UpdatedAt    time.Time `json:"updated_at"`
-				Email        string    `json:"email"`
-				Token        string    `json:"token"`
-				RefreshToken string    `json:"refresh_token"`
-				IsChirpyRed  bool      `json:"is_chirpy_red"`
+			w.Header().Set("Content-Type", "application/json")
+			moderator, ok := authenticateModerator(w, r, &config)
+			if !ok {
+				return
 			}
-			w.Header().Add("Content-Type", "application/json")
-			decoder := json.NewDecoder(r.Body)
-			params := parameters{}
-			err := decoder.Decode(&params)
+			reportID, err := uuid.Parse(r.PathValue("id"))
 			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid report id",
+				})
 				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
 				return
 			}
-			user, dbErr := config.DbQueries.GetUserByEmail(r.Context(), params.Email)
-			if dbErr != nil {
-				w.WriteHeader(http.StatusNotFound)
-				return
+			// POST assigns the report to the moderator in the body, or to
+			// the caller without one; DELETE unassigns it
+			assignee := uuid.NullUUID{}
+			if r.Method == "POST" {
+				type parameters struct {
+					ModeratorID *uuid.UUID `json:"moderator_id"`
+				}
+				params := parameters{}
+				err := json.NewDecoder(r.Body).Decode(&params)
+				if err != nil && !errors.Is(err, io.EOF) {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "error marshalling JSON: " + err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				assignee = uuid.NullUUID{UUID: moderator.ID, Valid: true}
+				if params.ModeratorID != nil && *params.ModeratorID != moderator.ID {
+					other, err := config.DbQueries.GetUserById(r.Context(), *params.ModeratorID)
+					if err != nil || !other.IsModerator {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "reports can only be assigned to moderators",
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
+					assignee.UUID = other.ID
+				}
 			}
-			hashErr := auth.CheckPasswordHash(params.Password, user.HashedPassword)
-			if hashErr != nil {
-				marshal, _ := json.Marshal(utils.Message{
-					Message: "Incorrect email or password",
+			report, err := config.DbQueries.AssignReport(r.Context(), database.AssignReportParams{
+				ID:         reportID,
+				AssigneeID: assignee,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "report not found",
 				})
-				w.WriteHeader(http.StatusUnauthorized)
+				w.WriteHeader(http.StatusNotFound)
 				w.Write(marshal)
 				return
 			}
-			accessToken, JWTerr := auth.MakeJWT(user.ID, string(config.JwtSecret), time.Hour)
-			if JWTerr != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				return
-			}
-			refreshToken, err := auth.MakeRefreshToken()
 			if err != nil {
+				log.Printf("error assigning report %s: %v", reportID, err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			_, err = config.DbQueries.CreateRefreshToken(
-				r.Context(),
-				database.CreateRefreshTokenParams{
-					Token:     refreshToken,
-					UserID:    user.ID,
-					ExpiresAt: time.Now().Add(24 * 60 * time.Hour),
-				},
-			)
-			if err != nil {
-				return
-			}
-			data, err := json.Marshal(response{
-				ID:           user.ID,
-				Email:        user.Email,
-				CreatedAt:    user.CreatedAt,
-				UpdatedAt:    user.UpdatedAt,
-				Token:        accessToken,
-				RefreshToken: refreshToken,
-				IsChirpyRed:  user.IsChirpyRed,
-			})
+			dat, err := json.Marshal(reportResponseFrom(report, true))
 			if err != nil {
+				log.Printf("error writing /admin/reports response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			w.Write(data)
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
 		},
 	)
 	go serveMux.HandleFunc(
-		"/api/refresh",
+		"/admin/reports/{id}/actions",
 		func(w http.ResponseWriter, r *http.Request) {
 			if r.Method != "POST" {
 				w.WriteHeader(http.StatusMethodNotAllowed)
 				return
 			}
-			type response struct {
-				Token string `json:"token"`
+			w.Header().Set("Content-Type", "application/json")
+			moderator, ok := authenticateModerator(w, r, &config)
+			if !ok {
+				return
 			}
-			bearerToken, err := auth.GetBearerToken(r.Header)
+			reportID, err := uuid.Parse(r.PathValue("id"))
 			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid report id",
+				})
 				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
 				return
 			}
-			refreshToken, err := config.DbQueries.GetRefreshTokenByToken(
-				r.Context(),
-				bearerToken,
-			)
-			if err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				return
+			type parameters struct {
+				Action string `json:"action"`
+				Note   string `json:"note"`
+				// SuspendedUntil is for suspend_user, it defaults to
+				// reports.DefaultSuspension from now
+				SuspendedUntil *time.Time `json:"suspended_until"`
 			}
-			if refreshToken.RevokedAt.Valid {
-				w.WriteHeader(http.StatusUnauthorized)
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
 				return
 			}
-			if refreshToken.ExpiresAt.Before(time.Now()) {
-				w.WriteHeader(http.StatusUnauthorized)
+			outcome, err := reports.Outcome(params.Action)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
 				return
 			}
-			accessToken, err := auth.MakeJWT(refreshToken.UserID, string(config.JwtSecret), time.Hour)
-			if err != nil {
+			report, err := config.DbQueries.GetReportById(r.Context(), reportID)
+			if errors.Is(err, sql.ErrNoRows) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "report not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
 				return
 			}
-			marshal, err := json.Marshal(response{
-				Token: accessToken,
-			})
 			if err != nil {
+				log.Printf("error retrieving report %s: %v", reportID, err)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			w.Write(marshal)
-			return
-		},
-	)
-
-	go serveMux.HandleFunc(
-		"/api/revoke",
-		func(w http.ResponseWriter, r *http.Request) {
-			if r.Method != "POST" {
-				w.WriteHeader(http.StatusMethodNotAllowed)
+			if report.Status != reports.StatusOpen {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "report is already resolved",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
 				return
 			}
-			bearerToken, err := auth.GetBearerToken(r.Header)
-			if err != nil {
+			if params.Action == reports.ActionDeleteChirp && !report.ChirpID.Valid {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: reports.ErrNotAChirp.Error(),
+				})
 				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
 				return
 			}
-			refreshToken, err := config.DbQueries.GetRefreshTokenByToken(r.Context(), bearerToken)
+			suspendedUntil := time.Now().UTC().Add(reports.DefaultSuspension)
+			if params.SuspendedUntil != nil {
+				if !params.SuspendedUntil.After(time.Now()) {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "suspended_until must be in the future",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				suspendedUntil = params.SuspendedUntil.UTC()
+			}
+
+			// the chirp goes first: deleting it again is harmless, so if
+			// recording the action fails the moderator can just retry
+			if params.Action == reports.ActionDeleteChirp {
+				reported, findErr := config.DbQueries.RetrieveChirpById(r.Context(), report.ChirpID.UUID)
+				if err := deleteChirp(r.Context(), &config, report.ChirpID.UUID); err != nil {
+					log.Printf("error deleting reported chirp %s: %v", report.ChirpID.UUID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				err := config.DbQueries.DeleteRechirpsOfChirp(r.Context(), report.ChirpID)
+				if err != nil {
+					log.Printf("error deleting rechirps of %s: %v", report.ChirpID.UUID, err)
+				}
+				if findErr == nil {
+					streamDeletedChirp(r.Context(), chirpStream, reported)
+				}
+			}
+			tx, err := db.BeginTx(r.Context(), nil)
 			if err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
+				log.Printf("error starting report action transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			resolvedOutcome := sql.NullString{String: outcome, Valid: true}
+			resolvedBy := uuid.NullUUID{UUID: moderator.ID, Valid: true}
+			resolved, err := qtx.ResolveReport(r.Context(), database.ResolveReportParams{
+				ID:         report.ID,
+				Outcome:    resolvedOutcome,
+				ResolvedBy: resolvedBy,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "report is already resolved",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
 				return
 			}
-			err = config.DbQueries.UpdateRefreshTokenByToken(
-				r.Context(),
-				database.UpdateRefreshTokenByTokenParams{
-					Token:     refreshToken.Token,
-					CreatedAt: refreshToken.CreatedAt,
-					UserID:    refreshToken.UserID,
-					ExpiresAt: time.Now().Add(24 * 60 * time.Hour),
-					RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
-				},
-			)
 			if err != nil {
+				log.Printf("error resolving report %s: %v", report.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			w.WriteHeader(http.StatusNoContent)
+			action := database.CreateModerationActionParams{
+				ModeratorID:   moderator.ID,
+				ReportID:      uuid.NullUUID{UUID: report.ID, Valid: true},
+				Action:        params.Action,
+				TargetUserID:  uuid.NullUUID{UUID: report.UserID, Valid: true},
+				TargetChirpID: report.ChirpID,
+				Note:          strings.TrimSpace(params.Note),
+			}
+			// other open reports about the same chirp or user are settled by
+			// the same action
+			var others []database.Report
+			switch params.Action {
+			case reports.ActionDeleteChirp:
+				others, err = qtx.ResolveReportsByChirp(r.Context(), database.ResolveReportsByChirpParams{
+					ChirpID:    report.ChirpID,
+					Outcome:    resolvedOutcome,
+					ResolvedBy: resolvedBy,
+				})
+			case reports.ActionSuspendUser:
+				_, err = qtx.SuspendUser(r.Context(), database.SuspendUserParams{
+					ID:               report.UserID,
+					SuspendedUntil:   sql.NullTime{Time: suspendedUntil, Valid: true},
+					SuspensionReason: suspensionReason(report.Reason, action.Note),
+				})
+				if err == nil {
+					err = qtx.RevokeRefreshTokensByUser(r.Context(), report.UserID)
+				}
+				if err == nil {
+					others, err = qtx.ResolveReportsByUser(r.Context(), database.ResolveReportsByUserParams{
+						UserID:     report.UserID,
+						Outcome:    resolvedOutcome,
+						ResolvedBy: resolvedBy,
+					})
+				}
+			}
+			if err != nil {
+				log.Printf("error applying %s for report %s: %v", params.Action, report.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if _, err := qtx.CreateModerationAction(r.Context(), action); err != nil {
+				log.Printf("error recording moderation action: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			// reporters learn the outcome from their notifications
+			for _, report := range append([]database.Report{resolved}, others...) {
+				err := notify(r.Context(), qtx, database.CreateNotificationParams{
+					UserID:   report.ReporterID,
+					Type:     notifications.TypeReportResolved,
+					ReportID: uuid.NullUUID{UUID: report.ID, Valid: true},
+				})
+				if err != nil {
+					log.Printf("error notifying %s of report %s: %v", report.ReporterID, report.ID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing report action transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(reportResponseFrom(resolved, true))
+			if err != nil {
+				log.Printf("error writing /admin/reports response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
 		},
 	)
-	go serveMux.HandleFunc("/api/polka/webhooks", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
+	go serveMux.HandleFunc(
+		"/admin/users/{id}/suspension",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
 			w.Header().Set("Content-Type", "application/json")
-			type parameters struct {
-				Event string `json:"event"`
-				Data  struct {
-					UserID string `json:"user_id"`
-				} `json:"data"`
+			moderator, ok := authenticateModerator(w, r, &config)
+			if !ok {
+				return
 			}
-			apiKey, apiKeyErr := auth.GetAPIKey(r.Header)
-			if apiKeyErr != nil {
-				marshal, _ := json.Marshal(utils.Message{
-					Message: "Invalid key",
+			userID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid user id",
 				})
-
-				w.WriteHeader(http.StatusUnauthorized)
+				w.WriteHeader(http.StatusBadRequest)
 				w.Write(marshal)
 				return
 			}
+			type parameters struct {
+				// SuspendedUntil defaults to reports.DefaultSuspension from now
+				SuspendedUntil *time.Time `json:"suspended_until"`
+				Reason         string     `json:"reason"`
+			}
+			params := parameters{}
+			if r.Method == "POST" {
+				if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "error marshalling JSON: " + err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				params.Reason = strings.TrimSpace(params.Reason)
+				if params.Reason == "" {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "a suspension needs a reason, it's shown to the user",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				if params.SuspendedUntil != nil && !params.SuspendedUntil.After(time.Now()) {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "suspended_until must be in the future",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			}
 
-			if apiKey != config.PolkaKey {
-				marshal, _ := json.Marshal(utils.Message{
-					Message: "Invalid key",
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting suspension transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			var user database.User
+			action := reports.ActionLiftSuspension
+			if r.Method == "POST" {
+				action = reports.ActionSuspendUser
+				suspendedUntil := time.Now().UTC().Add(reports.DefaultSuspension)
+				if params.SuspendedUntil != nil {
+					suspendedUntil = params.SuspendedUntil.UTC()
+				}
+				user, err = qtx.SuspendUser(r.Context(), database.SuspendUserParams{
+					ID:               userID,
+					SuspendedUntil:   sql.NullTime{Time: suspendedUntil, Valid: true},
+					SuspensionReason: params.Reason,
 				})
-				w.WriteHeader(http.StatusUnauthorized)
+				if err == nil {
+					// access tokens run out within the hour, refresh tokens
+					// would keep the user signed in for weeks
+					err = qtx.RevokeRefreshTokensByUser(r.Context(), userID)
+				}
+			} else {
+				user, err = qtx.LiftSuspension(r.Context(), userID)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
 				w.Write(marshal)
 				return
 			}
-
-			decoder := json.NewDecoder(r.Body)
-			params := parameters{}
-			err := decoder.Decode(&params)
 			if err != nil {
-				log.Printf("Failed decoding body params")
-				w.WriteHeader(http.StatusBadRequest)
+				log.Printf("error updating suspension of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			if params.Event != "user.upgraded" {
-				w.WriteHeader(http.StatusNoContent)
+			_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+				ModeratorID:  moderator.ID,
+				Action:       action,
+				TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+				Note:         params.Reason,
+			})
+			if err != nil {
+				log.Printf("error recording moderation action: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			userID := params.Data.UserID
-			parsedUID, parseErr := uuid.Parse(userID)
-			if parseErr != nil {
-				log.Printf("Failed parsing userID %s", userID)
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing suspension transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(userRestrictionsFrom(user))
+			if err != nil {
+				log.Printf("error writing /admin/users/{id}/suspension response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/admin/users/{id}/shadow-ban",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			moderator, ok := authenticateModerator(w, r, &config)
+			if !ok {
+				return
+			}
+			userID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid user id",
+				})
 				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
 				return
 			}
-			user, getUserErr := config.DbQueries.GetUserById(r.Context(), parsedUID)
-			if getUserErr != nil {
+			action := reports.ActionShadowBan
+			if r.Method == "DELETE" {
+				action = reports.ActionLiftShadowBan
+			}
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting shadow-ban transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			user, err := qtx.SetShadowBanned(r.Context(), database.SetShadowBannedParams{
+				ID:           userID,
+				ShadowBanned: r.Method == "POST",
+			})
+			if errors.Is(err, sql.ErrNoRows) {
 				marshal, _ := json.Marshal(utils.Error{
 					Error: "user not found",
 				})
-				log.Printf("Failed getting user %s", parsedUID)
 				w.WriteHeader(http.StatusNotFound)
 				w.Write(marshal)
 				return
 			}
-			_, updateUserErr := config.DbQueries.UpdateUserByID(
-				r.Context(),
-				database.UpdateUserByIDParams{
-					ID:             parsedUID,
-					CreatedAt:      user.CreatedAt,
-					Email:          user.Email,
-					HashedPassword: user.HashedPassword,
-					IsChirpyRed:    true,
-				},
-			)
-			if updateUserErr != nil {
-				log.Printf("Failed updating user %s", user.Email)
+			if err != nil {
+				log.Printf("error updating shadow-ban of %s: %v", userID, err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			w.WriteHeader(http.StatusNoContent)
-		} else {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
-	})
+			_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+				ModeratorID:  moderator.ID,
+				Action:       action,
+				TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+			})
+			if err != nil {
+				log.Printf("error recording moderation action: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing shadow-ban transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(userRestrictionsFrom(user))
+			if err != nil {
+				log.Printf("error writing /admin/users/{id}/shadow-ban response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/chirps",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				type parameters struct {
+					Body          string     `json:"body"`
+					UserID        uuid.UUID  `json:"user_id"`
+					QuotedChirpID *uuid.UUID `json:"quoted_chirp_id"`
+					// PublishAt schedules the chirp instead of posting it
+					// right away, for Chirpy Red users
+					PublishAt  *time.Time `json:"publish_at"`
+					Poll       *newPoll   `json:"poll"`
+					Visibility string     `json:"visibility"`
+				}
 
-	err = server.ListenAndServe()
-	if err != nil {
-		_ = fmt.Errorf("server isn't starting")
+				params := parameters{}
+				var images []media.Image
+				// chirps with images are sent as multipart/form-data with the
+				// same fields as form values and the images as "images" files
+				if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+					r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadBytes)
+					err := r.ParseMultipartForm(media.MaxImageBytes)
+					if err != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "invalid multipart form: " + err.Error(),
+						})
+						var maxBytesErr *http.MaxBytesError
+						if errors.As(err, &maxBytesErr) {
+							w.WriteHeader(http.StatusRequestEntityTooLarge)
+						} else {
+							w.WriteHeader(http.StatusBadRequest)
+						}
+						w.Write(marshal)
+						return
+					}
+					defer r.MultipartForm.RemoveAll()
+					params.Body = r.FormValue("body")
+					params.Visibility = r.FormValue("visibility")
+					if quoted := r.FormValue("quoted_chirp_id"); quoted != "" {
+						parsed, err := uuid.Parse(quoted)
+						if err != nil {
+							marshal, _ := json.Marshal(utils.Error{
+								Error: "invalid quoted chirp id",
+							})
+							w.WriteHeader(http.StatusBadRequest)
+							w.Write(marshal)
+							return
+						}
+						params.QuotedChirpID = &parsed
+					}
+					if publishAt := r.FormValue("publish_at"); publishAt != "" {
+						parsed, err := time.Parse(time.RFC3339, publishAt)
+						if err != nil {
+							marshal, _ := json.Marshal(utils.Error{
+								Error: "invalid publish_at",
+							})
+							w.WriteHeader(http.StatusBadRequest)
+							w.Write(marshal)
+							return
+						}
+						params.PublishAt = &parsed
+					}
+					if options := r.MultipartForm.Value["poll_options"]; len(options) > 0 {
+						closesAt, err := time.Parse(time.RFC3339, r.FormValue("poll_closes_at"))
+						if err != nil {
+							marshal, _ := json.Marshal(utils.Error{
+								Error: "invalid poll_closes_at",
+							})
+							w.WriteHeader(http.StatusBadRequest)
+							w.Write(marshal)
+							return
+						}
+						params.Poll = &newPoll{Options: options, ClosesAt: closesAt}
+					}
+					images, err = media.ReadUploads(r.MultipartForm.File["images"])
+					if err != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: err.Error(),
+						})
+						if errors.Is(err, media.ErrImageTooLarge) {
+							w.WriteHeader(http.StatusRequestEntityTooLarge)
+						} else {
+							w.WriteHeader(http.StatusBadRequest)
+						}
+						w.Write(marshal)
+						return
+					}
+				} else {
+					decoder := json.NewDecoder(r.Body)
+					err := decoder.Decode(&params)
+					if err != nil {
+						dat, err := json.Marshal(utils.Error{
+							Error: "error marshalling JSON: " + err.Error(),
+						})
+						if err != nil {
+							log.Printf("error writing /api/chirps response: %v", err)
+							w.WriteHeader(http.StatusInternalServerError)
+							return
+						}
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(dat)
+						return
+					}
+				}
+
+				w.Header().Add("Content-Type", "application/json")
+				bearerToken, bearerTokenErr := auth.GetBearerToken(r.Header)
+				if bearerTokenErr != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: err.Error(),
+					})
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write(marshal)
+					return
+				}
+				tx, err := db.BeginTx(r.Context(), nil)
+				if err != nil {
+					log.Printf("error starting chirp transaction: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				defer tx.Rollback()
+				chirp, err := postChirp(r.Context(), config.DbQueries.WithTx(tx), userID, newChirp{
+					Body:          params.Body,
+					QuotedChirpID: params.QuotedChirpID,
+					PublishAt:     params.PublishAt,
+					Poll:          params.Poll,
+					Visibility:    params.Visibility,
+				})
+				if err != nil {
+					writeRequestError(w, err, "error creating chirp")
+					return
+				}
+				if err := tx.Commit(); err != nil {
+					log.Printf("error committing chirp: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if err := storeChirpAttachments(r.Context(), &config, chirp, images); err != nil {
+					log.Printf("error storing attachments of chirp %s: %v", chirp.ID, err)
+					// a chirp missing some of its images isn't what was posted
+					deleteChirp(r.Context(), &config, chirp.ID)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if len(images) > 0 {
+					mediaWorker.Notify()
+				}
+				streamCreatedChirp(r.Context(), &config, chirpStream, chirp)
+				retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, []database.Chirp{chirp})
+				if err != nil {
+					log.Printf("error building /api/chirps response: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				dat, err := json.Marshal(retChirps[0])
+				if err != nil {
+					log.Printf("error writing /validate_chirp response: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write(dat)
+				return
+			} else if r.Method == "GET" {
+				author := r.URL.Query().Get("author_id")
+				sortAsc := r.URL.Query().Get("sort")
+				w.Header().Add("Content-Type", "application/json")
+				// shadow-banned users still see their own chirps, so they
+				// don't notice nobody else does
+				viewerID := optionalViewer(r, &config)
+				var chirps []database.Chirp
+				var err error
+				if author != "" {
+					parsed, parseErr := uuid.Parse(author)
+					if parseErr != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "invalid author",
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
+					if sortAsc == "desc" {
+						chirps, err = config.DbQueries.RetrieveChirpsByAuthorDesc(r.Context(), database.RetrieveChirpsByAuthorDescParams{
+							UserID:   parsed,
+							ViewerID: viewerID,
+						})
+					} else {
+						chirps, err = config.DbQueries.RetrieveChirpsByAuthor(r.Context(), database.RetrieveChirpsByAuthorParams{
+							UserID:   parsed,
+							ViewerID: viewerID,
+						})
+					}
+					var pinned []uuid.UUID
+					if err == nil {
+						pinned, err = config.DbQueries.GetPinnedChirpIds(r.Context(), parsed)
+					}
+					if err != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "chirps by given author is not found",
+						})
+						w.WriteHeader(http.StatusNotFound)
+						w.Write(marshal)
+						return
+					}
+					// a profile starts with its pinned chirps
+					chirps = pins.First(chirps, pinned)
+				} else {
+					if sortAsc == "desc" {
+						chirps, err = config.DbQueries.RetrieveChirpsDesc(r.Context(), viewerID)
+					} else {
+						chirps, err = config.DbQueries.RetrieveChirps(r.Context(), viewerID)
+					}
+					if err != nil {
+						return
+					}
+				}
+				retChirps, err := chirpResponses(r.Context(), &config, viewerID, chirps)
+				if err == nil {
+					retChirps, err = hideMutedWords(r.Context(), &config, viewerID, retChirps)
+				}
+				if err != nil {
+					log.Printf("error building /api/chirps response: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				dat, err := json.Marshal(retChirps)
+				w.Write(dat)
+			}
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/chirps/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				id := r.PathValue("id")
+				if id != "" {
+					chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), uuid.MustParse(id))
+					if err != nil {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+
+					if chirp.Body == "" && !chirp.IsRechirp {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					viewerID := optionalViewer(r, &config)
+					if !chirpVisibleTo(r.Context(), config.DbQueries, chirp, viewerID) {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+
+					retChirps, err := chirpResponses(r.Context(), &config, viewerID, []database.Chirp{chirp})
+					if err != nil {
+						log.Printf("error building /api/chirps/{id} response: %v", err)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					dat, err := json.Marshal(retChirps[0])
+					if err != nil {
+						log.Printf("error writing /validate_chirp response: %v", err)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					w.Write(dat)
+					return
+				}
+			} else if r.Method == "DELETE" {
+				id := r.PathValue("id")
+				bearerToken, err := auth.GetBearerToken(r.Header)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid authentication token",
+					})
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write(marshal)
+					return
+				}
+				userID, validateJWTErr := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+				if validateJWTErr != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid authentication token",
+					})
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(marshal)
+					return
+				}
+				if id != "" {
+					chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), uuid.MustParse(id))
+					if err != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "chirp not found",
+						})
+						w.WriteHeader(http.StatusNotFound)
+						w.Write(marshal)
+						return
+					}
+					if userID != chirp.UserID {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "unauthorized action",
+						})
+						w.WriteHeader(http.StatusForbidden)
+						w.Write(marshal)
+						return
+					}
+					deleteChirpErr := deleteChirp(r.Context(), &config, chirp.ID)
+					if deleteChirpErr != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: "chirp not found",
+						})
+						w.WriteHeader(http.StatusNotFound)
+						w.Write(marshal)
+						return
+					}
+					// quotes keep pointing at the deleted chirp and render a tombstone,
+					// plain rechirps have nothing left to show so they go with it
+					deleteRechirpsErr := config.DbQueries.DeleteRechirpsOfChirp(
+						r.Context(),
+						uuid.NullUUID{UUID: chirp.ID, Valid: true},
+					)
+					if deleteRechirpsErr != nil {
+						log.Printf("error deleting rechirps of %s: %v", chirp.ID, deleteRechirpsErr)
+					}
+					streamDeletedChirp(r.Context(), chirpStream, chirp)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				} else {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid chirp id",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			} else {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/chirps/{id}/rechirp",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			chirpID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid chirp id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			original, err := config.DbQueries.RetrieveChirpById(r.Context(), chirpID)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "chirp not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "POST" {
+				if !chirpVisibleTo(r.Context(), config.DbQueries, original, uuid.NullUUID{UUID: userID, Valid: true}) {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "chirp not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				if original.UserID != userID && !visibility.Shareable(original.Visibility) {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "followers-only chirps can't be rechirped",
+					})
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(marshal)
+					return
+				}
+			}
+			// rechirping a rechirp points at the chirp it was rechirping
+			originalID := uuid.NullUUID{UUID: original.ID, Valid: true}
+			if original.IsRechirp {
+				originalID = original.QuotedChirpID
+			}
+			existing, existingErr := config.DbQueries.RetrieveRechirpByUser(
+				r.Context(),
+				database.RetrieveRechirpByUserParams{
+					UserID:        userID,
+					QuotedChirpID: originalID,
+				},
+			)
+			if r.Method == "DELETE" {
+				if existingErr != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "rechirp not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				err = config.DbQueries.DeleteChirpById(r.Context(), existing.ID)
+				if err != nil {
+					log.Printf("error deleting rechirp %s: %v", existing.ID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				streamDeletedChirp(r.Context(), chirpStream, existing)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			alreadyRechirped := func() {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "chirp already rechirped",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+			}
+			if existingErr == nil {
+				alreadyRechirped()
+				return
+			}
+			rechirp, err := config.DbQueries.CreateChirp(r.Context(), database.CreateChirpParams{
+				UserID:        userID,
+				QuotedChirpID: originalID,
+				IsRechirp:     true,
+				Visibility:    visibility.Public,
+			})
+			// a rechirp sent at the same time got past the check above
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				alreadyRechirped()
+				return
+			}
+			if err != nil {
+				log.Printf("error creating rechirp: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := precomputedTimeline.Publish(r.Context(), rechirp); err != nil {
+				log.Printf("error publishing rechirp %s to timelines: %v", rechirp.ID, err)
+			}
+			streamCreatedChirp(r.Context(), &config, chirpStream, rechirp)
+			rechirped := original
+			if original.IsRechirp {
+				rechirped, err = config.DbQueries.RetrieveChirpById(r.Context(), originalID.UUID)
+			}
+			if err == nil {
+				err = notify(r.Context(), config.DbQueries, database.CreateNotificationParams{
+					UserID:  rechirped.UserID,
+					ActorID: uuid.NullUUID{UUID: userID, Valid: true},
+					Type:    notifications.TypeRechirp,
+					ChirpID: uuid.NullUUID{UUID: rechirped.ID, Valid: true},
+				})
+			}
+			if err != nil {
+				log.Printf("error notifying of rechirp %s: %v", rechirp.ID, err)
+			}
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, []database.Chirp{rechirp})
+			if err != nil {
+				log.Printf("error building /api/chirps/{id}/rechirp response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retChirps[0])
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/ws",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			// browsers can't set headers on a WebSocket, so the token can
+			// also come as ?access_token=
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				bearerToken = r.URL.Query().Get("access_token")
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			err = socketHub.Serve(w, r, userID)
+			if errors.Is(err, realtime.ErrShuttingDown) {
+				w.Header().Set("Retry-After", "5")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if err != nil {
+				log.Printf("error opening socket for %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/stream/chirps",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			// new public chirps and deletions as server-sent events,
+			// ?author_id= narrows them to one author
+			authorID := uuid.NullUUID{}
+			if author := r.URL.Query().Get("author_id"); author != "" {
+				parsed, err := uuid.Parse(author)
+				if err != nil {
+					w.Header().Set("Content-Type", "application/json")
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid author",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				authorID = uuid.NullUUID{UUID: parsed, Valid: true}
+			}
+			// what the viewer blocked or muted when the stream opened stays
+			// hidden for as long as it's open
+			hidden := make(map[uuid.UUID]bool)
+			if viewerID := optionalViewer(r, &config); viewerID.Valid {
+				hiddenIDs, err := config.DbQueries.GetHiddenAuthorIds(r.Context(), viewerID.UUID)
+				if err != nil {
+					log.Printf("error getting authors hidden from %s: %v", viewerID.UUID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				for _, id := range hiddenIDs {
+					hidden[id] = true
+				}
+			}
+			// an unparsable Last-Event-ID starts over
+			lastEventID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+			sub, missed, err := chirpBroker.Subscribe(lastEventID)
+			if errors.Is(err, stream.ErrTooManyStreams) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", "30")
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write(marshal)
+				return
+			}
+			defer chirpBroker.Unsubscribe(sub)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+			controller := http.NewResponseController(w)
+			send := func(event stream.Event) error {
+				if event.Visibility != visibility.Public || hidden[event.AuthorID] || (authorID.Valid && event.AuthorID != authorID.UUID) {
+					return nil
+				}
+				_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+				if err != nil {
+					return err
+				}
+				return controller.Flush()
+			}
+			for _, event := range missed {
+				if err := send(event); err != nil {
+					return
+				}
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+			heartbeat := time.NewTicker(stream.HeartbeatInterval)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case <-r.Context().Done():
+					return
+				case event, ok := <-sub.C:
+					// dropped for falling behind, the client resumes
+					// with Last-Event-ID
+					if !ok {
+						return
+					}
+					if err := send(event); err != nil {
+						return
+					}
+				case <-heartbeat.C:
+					if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+						return
+					}
+					if err := controller.Flush(); err != nil {
+						return
+					}
+				}
+			}
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/chirps/{id}/pin",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			chirpID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid chirp id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				rows, err := config.DbQueries.UnpinChirp(r.Context(), database.UnpinChirpParams{
+					UserID:  userID,
+					ChirpID: chirpID,
+				})
+				if err != nil {
+					log.Printf("error unpinning chirp %s: %v", chirpID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if rows == 0 {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "chirp is not pinned",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), chirpID)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "chirp not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			if chirp.UserID != userID || chirp.IsRechirp {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "you can only pin your own chirps",
+				})
+				w.WriteHeader(http.StatusForbidden)
+				w.Write(marshal)
+				return
+			}
+			pinned, err := config.DbQueries.GetPinnedChirpIds(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting pinned chirps of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			for _, id := range pinned {
+				if id == chirpID {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "chirp is already pinned",
+					})
+					w.WriteHeader(http.StatusConflict)
+					w.Write(marshal)
+					return
+				}
+			}
+			// the insert checks the limit again with the user's pins locked,
+			// so two pins racing each other can't both take the last spot
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting pin transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			if err := qtx.LockPinnedChirps(r.Context(), userID); err != nil {
+				log.Printf("error locking pinned chirps of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			rows, err := qtx.PinChirp(r.Context(), database.PinChirpParams{
+				UserID:  userID,
+				ChirpID: chirpID,
+				MaxPins: int32(maxPinnedChirps),
+			})
+			if err != nil {
+				log.Printf("error pinning chirp %s: %v", chirpID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing pin of %s: %v", chirpID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if rows == 0 {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: fmt.Sprintf("you can pin at most %d chirps", maxPinnedChirps),
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/me/pins",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "PUT" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "PUT" {
+				// reorders the pins, chirp_ids has to list every pinned chirp
+				type parameters struct {
+					ChirpIDs []uuid.UUID `json:"chirp_ids"`
+				}
+				params := parameters{}
+				if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "error marshalling JSON: " + err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				tx, err := db.BeginTx(r.Context(), nil)
+				if err != nil {
+					log.Printf("error starting pin order transaction: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				defer tx.Rollback()
+				qtx := config.DbQueries.WithTx(tx)
+				if err := qtx.LockPinnedChirps(r.Context(), userID); err != nil {
+					log.Printf("error locking pinned chirps of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				pinned, err := qtx.GetPinnedChirpIds(r.Context(), userID)
+				if err != nil {
+					log.Printf("error getting pinned chirps of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				listed := make(map[uuid.UUID]bool, len(params.ChirpIDs))
+				for _, id := range params.ChirpIDs {
+					listed[id] = true
+				}
+				same := len(listed) == len(params.ChirpIDs) && len(listed) == len(pinned)
+				for _, id := range pinned {
+					same = same && listed[id]
+				}
+				if !same {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "chirp_ids must list each pinned chirp once",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				for i, id := range params.ChirpIDs {
+					_, err := qtx.SetPinPosition(r.Context(), database.SetPinPositionParams{
+						UserID:   userID,
+						ChirpID:  id,
+						Position: int32(i),
+					})
+					if err != nil {
+						log.Printf("error ordering pinned chirps of %s: %v", userID, err)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+				}
+				if err := tx.Commit(); err != nil {
+					log.Printf("error committing pin order of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			pinned, err := config.DbQueries.GetPinnedChirpIds(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting pinned chirps of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			chirps := []database.Chirp{}
+			if len(pinned) > 0 {
+				chirps, err = config.DbQueries.RetrieveChirpsByIds(r.Context(), database.RetrieveChirpsByIdsParams{
+					Ids:      pinned,
+					ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+				})
+				if err != nil {
+					log.Printf("error getting pinned chirps of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, pins.First(chirps, pinned))
+			if err != nil {
+				log.Printf("error building /api/users/me/pins response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retChirps)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/chirps/{id}/bookmark",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			chirpID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid chirp id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				rows, err := config.DbQueries.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+					UserID:  userID,
+					ChirpID: chirpID,
+				})
+				if err != nil {
+					log.Printf("error deleting bookmark of %s: %v", chirpID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if rows == 0 {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "chirp is not bookmarked",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			// bookmarking a bookmarked chirp moves it to collection_id, or out
+			// of its collection without one
+			type parameters struct {
+				CollectionID *uuid.UUID `json:"collection_id"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			viewerID := uuid.NullUUID{UUID: userID, Valid: true}
+			chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), chirpID)
+			if err == nil && !chirpVisibleTo(r.Context(), config.DbQueries, chirp, viewerID) {
+				err = sql.ErrNoRows
+			}
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "chirp not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			collectionID := uuid.NullUUID{}
+			if params.CollectionID != nil {
+				collection, err := config.DbQueries.GetBookmarkCollection(r.Context(), database.GetBookmarkCollectionParams{
+					ID:     *params.CollectionID,
+					UserID: userID,
+				})
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "collection not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				collectionID = uuid.NullUUID{UUID: collection.ID, Valid: true}
+			}
+			bookmark, err := config.DbQueries.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+				UserID:       userID,
+				ChirpID:      chirp.ID,
+				CollectionID: collectionID,
+			})
+			if err != nil {
+				log.Printf("error bookmarking %s: %v", chirp.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(bookmarkResponseFrom(bookmark))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/bookmarks",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Chirps     []chirpResponse `json:"chirps"`
+				NextCursor string          `json:"next_cursor,omitempty"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			pageSize := int64(timeline.DefaultPageSize)
+			if limit := r.URL.Query().Get("limit"); limit != "" {
+				pageSize, err = strconv.ParseInt(limit, 10, 32)
+				if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			}
+			params := database.RetrieveBookmarksParams{
+				UserID:   userID,
+				PageSize: int32(pageSize),
+			}
+			// ?collection_id=<id> lists a collection, ?collection_id=none the
+			// bookmarks outside of any, and without it every bookmark
+			switch collection := r.URL.Query().Get("collection_id"); collection {
+			case "":
+			case "none":
+				params.Uncollected = true
+			default:
+				collectionID, err := uuid.Parse(collection)
+				if err == nil {
+					_, err = config.DbQueries.GetBookmarkCollection(r.Context(), database.GetBookmarkCollectionParams{
+						ID:     collectionID,
+						UserID: userID,
+					})
+				}
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "collection not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				params.CollectionID = uuid.NullUUID{UUID: collectionID, Valid: true}
+			}
+			if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+				cursor, err := timeline.ParseCursor(rawCursor)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+				params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+			}
+			found, err := config.DbQueries.RetrieveBookmarks(r.Context(), params)
+			if err != nil {
+				log.Printf("error getting bookmarks of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			chirpIDs := make([]uuid.UUID, len(found))
+			for i, bookmark := range found {
+				chirpIDs[i] = bookmark.ChirpID
+			}
+			chirps := []database.Chirp{}
+			if len(chirpIDs) > 0 {
+				chirps, err = config.DbQueries.RetrieveChirpsByIds(r.Context(), database.RetrieveChirpsByIdsParams{
+					Ids:      chirpIDs,
+					ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+				})
+				if err != nil {
+					log.Printf("error getting bookmarked chirps of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			// in the order they were bookmarked
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, pins.First(chirps, chirpIDs))
+			if err != nil {
+				log.Printf("error building /api/bookmarks response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			ret := response{Chirps: retChirps}
+			if len(found) == int(pageSize) {
+				last := found[len(found)-1]
+				ret.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ChirpID}.String()
+			}
+			dat, err := json.Marshal(ret)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/bookmarks/collections",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "GET" {
+				collections, err := config.DbQueries.GetBookmarkCollections(r.Context(), userID)
+				if err != nil {
+					log.Printf("error getting bookmark collections of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				retCollections := make([]bookmarkCollectionResponse, len(collections))
+				for i, collection := range collections {
+					retCollections[i] = bookmarkCollectionResponseFrom(collection)
+				}
+				dat, err := json.Marshal(retCollections)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(dat)
+				return
+			}
+			type parameters struct {
+				Name string `json:"name"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			name, err := bookmarks.NormalizeName(params.Name)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			count, err := config.DbQueries.CountBookmarkCollections(r.Context(), userID)
+			if err != nil {
+				log.Printf("error counting bookmark collections of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if count >= bookmarks.MaxCollectionsPerUser {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: bookmarks.ErrTooManyCollections.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			collection, err := config.DbQueries.CreateBookmarkCollection(r.Context(), database.CreateBookmarkCollectionParams{
+				UserID: userID,
+				Name:   name,
+			})
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "a collection with this name already exists",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error creating bookmark collection for %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(bookmarkCollectionResponseFrom(collection))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/bookmarks/collections/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "PUT" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			collectionID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid collection id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				// its bookmarks are kept, outside of any collection
+				rows, err := config.DbQueries.DeleteBookmarkCollection(r.Context(), database.DeleteBookmarkCollectionParams{
+					ID:     collectionID,
+					UserID: userID,
+				})
+				if err != nil {
+					log.Printf("error deleting bookmark collection %s: %v", collectionID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if rows == 0 {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			type parameters struct {
+				Name string `json:"name"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			name, err := bookmarks.NormalizeName(params.Name)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			collection, err := config.DbQueries.RenameBookmarkCollection(r.Context(), database.RenameBookmarkCollectionParams{
+				ID:     collectionID,
+				UserID: userID,
+				Name:   name,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "a collection with this name already exists",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error renaming bookmark collection %s: %v", collectionID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(bookmarkCollectionResponseFrom(collection))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/conversations",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "GET" {
+				type response struct {
+					Conversations []conversationResponse `json:"conversations"`
+					NextCursor    string                 `json:"next_cursor,omitempty"`
+				}
+				pageSize := int64(timeline.DefaultPageSize)
+				if limit := r.URL.Query().Get("limit"); limit != "" {
+					pageSize, err = strconv.ParseInt(limit, 10, 32)
+					if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
+				}
+				params := database.RetrieveConversationsParams{
+					UserID:   userID,
+					PageSize: int32(pageSize),
+				}
+				if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+					cursor, err := timeline.ParseCursor(rawCursor)
+					if err != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: err.Error(),
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
+					params.BeforeLastMessageAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+					params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+				}
+				rows, err := config.DbQueries.RetrieveConversations(r.Context(), params)
+				if err != nil {
+					log.Printf("error getting conversations of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				retConversations, err := conversationResponses(r.Context(), config.DbQueries, rows)
+				if err != nil {
+					log.Printf("error building /api/conversations response: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				ret := response{Conversations: retConversations}
+				if len(rows) == int(pageSize) {
+					last := rows[len(rows)-1]
+					ret.NextCursor = timeline.Cursor{CreatedAt: last.LastMessageAt, ID: last.ID}.String()
+				}
+				dat, err := json.Marshal(ret)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(dat)
+				return
+			}
+			// a conversation can start with its first message, starting a
+			// one-to-one conversation that already exists carries on with it
+			type parameters struct {
+				ParticipantIDs []uuid.UUID `json:"participant_ids"`
+				Body           string      `json:"body"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			participants, err := messages.Participants(userID, params.ParticipantIDs)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			body := ""
+			if params.Body != "" {
+				body, err = checkMessage(moderationFilter, params.Body)
+				if err != nil {
+					writeRequestError(w, err, "error checking message")
+					return
+				}
+			}
+			for _, participantID := range participants[1:] {
+				if _, err := config.DbQueries.GetUserById(r.Context(), participantID); err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "user not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				blocked, err := config.DbQueries.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+					UserID:  userID,
+					OtherID: participantID,
+				})
+				if err != nil {
+					log.Printf("error checking blocks between %s and %s: %v", userID, participantID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if blocked {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "you can't message this user",
+					})
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(marshal)
+					return
+				}
+				participantSettings, err := settings.Load(r.Context(), config.DbQueries, participantID)
+				if err != nil {
+					log.Printf("error getting settings of %s: %v", participantID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				allowed, err := reaches(r.Context(), config.DbQueries, participantSettings.MessagesFrom, participantID, userID)
+				if err != nil {
+					log.Printf("error checking whether %s takes messages from %s: %v", participantID, userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if !allowed {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "this user doesn't take messages from you",
+					})
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(marshal)
+					return
+				}
+			}
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting conversation transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			status := http.StatusCreated
+			var conversationID uuid.UUID
+			if len(participants) == 2 {
+				// direct_key is unique, if the pair already has a
+				// conversation, or another request starts it at the same
+				// time, nothing is inserted and that one is used
+				pair := database.CreateDirectConversationParams{UserID: userID, OtherID: participants[1]}
+				conversation, err := qtx.CreateDirectConversation(r.Context(), pair)
+				if errors.Is(err, sql.ErrNoRows) {
+					status = http.StatusOK
+					conversation, err = qtx.FindDirectConversation(r.Context(), database.FindDirectConversationParams(pair))
+				}
+				if err != nil {
+					log.Printf("error starting conversation with %s: %v", participants[1], err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				conversationID = conversation.ID
+			} else {
+				conversation, err := qtx.CreateConversation(r.Context())
+				if err != nil {
+					log.Printf("error creating conversation: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				conversationID = conversation.ID
+			}
+			if status == http.StatusCreated {
+				for _, participantID := range participants {
+					err := qtx.AddConversationParticipant(r.Context(), database.AddConversationParticipantParams{
+						ConversationID: conversationID,
+						UserID:         participantID,
+					})
+					if err != nil {
+						log.Printf("error adding %s to conversation %s: %v", participantID, conversationID, err)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+				}
+			}
+			if body != "" {
+				if _, err := sendMessage(r.Context(), qtx, conversationID, userID, body); err != nil {
+					log.Printf("error sending message to %s: %v", conversationID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			conversation, err := qtx.GetConversationForParticipant(r.Context(), database.GetConversationForParticipantParams{
+				UserID: userID,
+				ID:     conversationID,
+			})
+			if err != nil {
+				log.Printf("error getting conversation %s: %v", conversationID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing conversation: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retConversations, err := conversationResponses(r.Context(), config.DbQueries, []database.RetrieveConversationsRow{database.RetrieveConversationsRow(conversation)})
+			if err != nil {
+				log.Printf("error building /api/conversations response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retConversations[0])
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(status)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/conversations/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			conversation, ok := participantConversation(w, r, &config, userID)
+			if !ok {
+				return
+			}
+			retConversations, err := conversationResponses(r.Context(), config.DbQueries, []database.RetrieveConversationsRow{database.RetrieveConversationsRow(conversation)})
+			if err != nil {
+				log.Printf("error building /api/conversations/{id} response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retConversations[0])
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/conversations/{id}/messages",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			conversation, ok := participantConversation(w, r, &config, userID)
+			if !ok {
+				return
+			}
+			if r.Method == "GET" {
+				// newest first, like timelines
+				type response struct {
+					Messages   []messageResponse `json:"messages"`
+					NextCursor string            `json:"next_cursor,omitempty"`
+				}
+				pageSize := int64(timeline.DefaultPageSize)
+				if limit := r.URL.Query().Get("limit"); limit != "" {
+					pageSize, err = strconv.ParseInt(limit, 10, 32)
+					if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
+				}
+				params := database.RetrieveMessagesParams{
+					ConversationID: conversation.ID,
+					PageSize:       int32(pageSize),
+				}
+				if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+					cursor, err := timeline.ParseCursor(rawCursor)
+					if err != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: err.Error(),
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
+					params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+					params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+				}
+				found, err := config.DbQueries.RetrieveMessages(r.Context(), params)
+				if err != nil {
+					log.Printf("error getting messages of %s: %v", conversation.ID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				ret := response{Messages: make([]messageResponse, len(found))}
+				for i, message := range found {
+					ret.Messages[i] = messageResponseFrom(message)
+				}
+				if len(found) == int(pageSize) {
+					last := found[len(found)-1]
+					ret.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+				}
+				dat, err := json.Marshal(ret)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(dat)
+				return
+			}
+			type parameters struct {
+				Body string `json:"body"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			body, err := checkMessage(moderationFilter, params.Body)
+			if err != nil {
+				writeRequestError(w, err, "error checking message")
+				return
+			}
+			// a block between the sender and anyone in the conversation
+			// ends it for the sender
+			blocked, err := config.DbQueries.IsBlockedInConversation(r.Context(), database.IsBlockedInConversationParams{
+				UserID:         userID,
+				ConversationID: conversation.ID,
+			})
+			if err != nil {
+				log.Printf("error checking blocks in conversation %s: %v", conversation.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if blocked {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "you can't message this conversation",
+				})
+				w.WriteHeader(http.StatusForbidden)
+				w.Write(marshal)
+				return
+			}
+			// so is anyone in it no longer taking messages from the sender
+			participants, err := config.DbQueries.RetrieveParticipantsByConversationIds(r.Context(), []uuid.UUID{conversation.ID})
+			if err != nil {
+				log.Printf("error getting participants of conversation %s: %v", conversation.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			for _, participant := range participants {
+				if participant.UserID == userID {
+					continue
+				}
+				participantSettings, err := settings.Load(r.Context(), config.DbQueries, participant.UserID)
+				if err != nil {
+					log.Printf("error getting settings of %s: %v", participant.UserID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				allowed, err := reaches(r.Context(), config.DbQueries, participantSettings.MessagesFrom, participant.UserID, userID)
+				if err != nil {
+					log.Printf("error checking whether %s takes messages from %s: %v", participant.UserID, userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if !allowed {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "you can't message this conversation",
+					})
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(marshal)
+					return
+				}
+			}
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting message transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			message, err := sendMessage(r.Context(), config.DbQueries.WithTx(tx), conversation.ID, userID, body)
+			if err != nil {
+				log.Printf("error sending message to %s: %v", conversation.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing message: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(messageResponseFrom(message))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/conversations/{id}/read",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			conversationID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid conversation id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			rows, err := config.DbQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+				ConversationID: conversationID,
+				UserID:         userID,
+			})
+			if err != nil {
+				log.Printf("error marking conversation %s read: %v", conversationID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if rows == 0 {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "conversation not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/notifications",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Notifications []notificationResponse `json:"notifications"`
+				UnreadCount   int64                  `json:"unread_count"`
+				NextCursor    string                 `json:"next_cursor,omitempty"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			pageSize := int64(timeline.DefaultPageSize)
+			if limit := r.URL.Query().Get("limit"); limit != "" {
+				pageSize, err = strconv.ParseInt(limit, 10, 32)
+				if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			}
+			params := database.RetrieveNotificationGroupsParams{
+				UserID:   userID,
+				PageSize: int32(pageSize),
+			}
+			if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+				cursor, err := timeline.ParseCursor(rawCursor)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				params.BeforeLatestAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+				params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+			}
+			groups, err := config.DbQueries.RetrieveNotificationGroups(r.Context(), params)
+			if err != nil {
+				log.Printf("error getting notifications of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retNotifications, err := notificationResponses(r.Context(), config.DbQueries, userID, groups)
+			if err != nil {
+				log.Printf("error building /api/notifications response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			unread, err := config.DbQueries.CountUnreadNotifications(r.Context(), userID)
+			if err != nil {
+				log.Printf("error counting unread notifications of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			ret := response{Notifications: retNotifications, UnreadCount: unread}
+			if len(groups) == int(pageSize) {
+				last := groups[len(groups)-1]
+				ret.NextCursor = timeline.Cursor{CreatedAt: last.LatestAt, ID: last.LatestID}.String()
+			}
+			dat, err := json.Marshal(ret)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/notifications/read",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			// without ids every notification is marked read
+			type parameters struct {
+				IDs []string `json:"ids"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if params.IDs == nil {
+				_, err = config.DbQueries.MarkAllNotificationsRead(r.Context(), userID)
+			} else {
+				_, err = config.DbQueries.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+					UserID:   userID,
+					GroupIds: params.IDs,
+				})
+			}
+			if err != nil {
+				log.Printf("error marking notifications of %s read: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/notifications/{id}/read",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			// marking a notification that's already read, or gone with its
+			// chirp, read again isn't an error
+			_, err = config.DbQueries.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+				UserID:   userID,
+				GroupIds: []string{r.PathValue("id")},
+			})
+			if err != nil {
+				log.Printf("error marking notification %s read: %v", r.PathValue("id"), err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/polls/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			pollID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid poll id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			viewerID := optionalViewer(r, &config)
+			poll, ok := visiblePoll(w, r, &config, pollID, viewerID)
+			if !ok {
+				return
+			}
+			retPolls, err := pollResponses(r.Context(), config.DbQueries, viewerID, []database.Poll{poll})
+			if err != nil {
+				log.Printf("error building /api/polls/{id} response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retPolls[poll.ChirpID])
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/polls/{id}/votes",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			pollID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid poll id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			type parameters struct {
+				OptionID uuid.UUID `json:"option_id"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			viewerID := uuid.NullUUID{UUID: userID, Valid: true}
+			poll, ok := visiblePoll(w, r, &config, pollID, viewerID)
+			if !ok {
+				return
+			}
+			if polls.Closed(poll.ClosesAt, time.Now()) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "poll is closed",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			// the insert checks the option and the closing time itself, a
+			// vote racing the poll closing or a second vote adds no row
+			rows, err := config.DbQueries.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+				UserID:   userID,
+				PollID:   poll.ID,
+				OptionID: params.OptionID,
+			})
+			if err != nil {
+				log.Printf("error voting in poll %s: %v", poll.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retPolls, err := pollResponses(r.Context(), config.DbQueries, viewerID, []database.Poll{poll})
+			if err != nil {
+				log.Printf("error building /api/polls/{id}/votes response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retPoll := retPolls[poll.ChirpID]
+			if rows == 0 {
+				message := "option not found in this poll"
+				status := http.StatusBadRequest
+				if retPoll.VotedOptionID != nil {
+					message = "you already voted in this poll"
+					status = http.StatusConflict
+				} else if polls.Closed(poll.ClosesAt, time.Now()) {
+					message = "poll is closed"
+					status = http.StatusConflict
+				}
+				marshal, _ := json.Marshal(utils.Error{
+					Error: message,
+				})
+				w.WriteHeader(status)
+				w.Write(marshal)
+				return
+			}
+			dat, err := json.Marshal(retPoll)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/drafts",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "GET" {
+				found, err := config.DbQueries.GetDraftsByUser(r.Context(), userID)
+				if err != nil {
+					log.Printf("error getting drafts of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				retDrafts := make([]draftResponse, len(found))
+				for i, draft := range found {
+					retDrafts[i] = draftResponseFrom(draft)
+				}
+				dat, err := json.Marshal(retDrafts)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(dat)
+				return
+			}
+			params, ok := decodeDraft(w, r)
+			if !ok {
+				return
+			}
+			// the insert checks the limit with the user's drafts locked, so
+			// drafts saved at once can't go past it together
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting draft transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			if err := qtx.LockDrafts(r.Context(), userID); err != nil {
+				log.Printf("error locking drafts of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			draft, err := qtx.CreateDraft(r.Context(), database.CreateDraftParams{
+				UserID:        userID,
+				Body:          params.Body,
+				QuotedChirpID: params.QuotedChirpID,
+				MaxDrafts:     drafts.MaxPerUser,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: drafts.ErrTooMany.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error creating draft for %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing draft for %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(draftResponseFrom(draft))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/drafts/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "PUT" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			draftID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid draft id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				rows, err := config.DbQueries.DeleteDraft(r.Context(), database.DeleteDraftParams{
+					ID:     draftID,
+					UserID: userID,
+				})
+				if err != nil {
+					log.Printf("error deleting draft %s: %v", draftID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if rows == 0 {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			var draft database.Draft
+			if r.Method == "GET" {
+				draft, err = config.DbQueries.GetDraft(r.Context(), database.GetDraftParams{
+					ID:     draftID,
+					UserID: userID,
+				})
+			} else {
+				params, ok := decodeDraft(w, r)
+				if !ok {
+					return
+				}
+				draft, err = config.DbQueries.UpdateDraft(r.Context(), database.UpdateDraftParams{
+					ID:            draftID,
+					UserID:        userID,
+					Body:          params.Body,
+					QuotedChirpID: params.QuotedChirpID,
+				})
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("error getting draft %s: %v", draftID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(draftResponseFrom(draft))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/drafts/{id}/publish",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			draftID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid draft id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			// a draft can be scheduled and given a visibility on publishing,
+			// the body is optional
+			type parameters struct {
+				PublishAt  *time.Time `json:"publish_at"`
+				Visibility string     `json:"visibility"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+
+			// the draft is deleted in the transaction that posts it, so it's
+			// published once however often the request is sent, and is kept
+			// when the chirp is refused
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting publish transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			draft, err := qtx.TakeDraft(r.Context(), database.TakeDraftParams{
+				ID:     draftID,
+				UserID: userID,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("error taking draft %s: %v", draftID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			posted := newChirp{Body: draft.Body, PublishAt: params.PublishAt, Visibility: params.Visibility}
+			if draft.QuotedChirpID.Valid {
+				posted.QuotedChirpID = &draft.QuotedChirpID.UUID
+			}
+			chirp, err := postChirp(r.Context(), qtx, userID, posted)
+			if err != nil {
+				writeRequestError(w, err, "error publishing draft "+draftID.String())
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing publish of draft %s: %v", draftID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			streamCreatedChirp(r.Context(), &config, chirpStream, chirp)
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, []database.Chirp{chirp})
+			if err != nil {
+				log.Printf("error building /api/drafts/{id}/publish response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retChirps[0])
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/scheduled-chirps",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			chirps, err := config.DbQueries.RetrieveScheduledChirpsByAuthor(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting scheduled chirps of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+			if err != nil {
+				log.Printf("error building /api/scheduled-chirps response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retChirps)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/scheduled-chirps/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "PUT" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			chirpID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid chirp id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				attachments, err := config.DbQueries.RetrieveAttachmentsByChirpIds(r.Context(), []uuid.UUID{chirpID})
+				if err != nil {
+					log.Printf("error getting attachments of chirp %s: %v", chirpID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				// only deletes the chirp while it's still scheduled, a chirp
+				// the scheduler got to first is published and stays
+				rows, err := config.DbQueries.CancelScheduledChirp(r.Context(), database.CancelScheduledChirpParams{
+					ID:     chirpID,
+					UserID: userID,
+				})
+				if err != nil {
+					log.Printf("error cancelling scheduled chirp %s: %v", chirpID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if rows == 0 {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "scheduled chirp not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				deleteAttachmentFiles(r.Context(), &config, attachments)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			type parameters struct {
+				PublishAt *time.Time `json:"publish_at"`
+			}
+			params := parameters{}
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "error marshalling JSON: " + err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if params.PublishAt == nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "publish_at is required",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if err := scheduler.Validate(*params.PublishAt, time.Now()); err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			user, err := config.DbQueries.GetUserById(r.Context(), userID)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			if !user.IsChirpyRed {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: scheduler.ErrNotRed.Error(),
+				})
+				w.WriteHeader(http.StatusForbidden)
+				w.Write(marshal)
+				return
+			}
+			chirp, err := config.DbQueries.RescheduleChirp(r.Context(), database.RescheduleChirpParams{
+				ID:        chirpID,
+				UserID:    userID,
+				PublishAt: sql.NullTime{Time: params.PublishAt.UTC(), Valid: true},
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "scheduled chirp not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error rescheduling chirp %s: %v", chirpID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, []database.Chirp{chirp})
+			if err != nil {
+				log.Printf("error building /api/scheduled-chirps response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(retChirps[0])
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/tags/{tag}/chirps",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Tag        string          `json:"tag"`
+				Chirps     []chirpResponse `json:"chirps"`
+				NextCursor string          `json:"next_cursor,omitempty"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			pageSize := int64(timeline.DefaultPageSize)
+			var err error
+			if limit := r.URL.Query().Get("limit"); limit != "" {
+				pageSize, err = strconv.ParseInt(limit, 10, 32)
+				if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			}
+			viewerID := optionalViewer(r, &config)
+			params := database.RetrieveChirpsByTagParams{
+				ViewerID: viewerID,
+				PageSize: int32(pageSize),
+			}
+			if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+				cursor, err := timeline.ParseCursor(rawCursor)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+				params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+			}
+			tag, err := config.DbQueries.GetTagByName(r.Context(), hashtags.Normalize(r.PathValue("tag")))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "tag not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			params.TagID = tag.ID
+			chirps, err := config.DbQueries.RetrieveChirpsByTag(r.Context(), params)
+			if err != nil {
+				log.Printf("error getting chirps tagged %s: %v", tag.Name, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retChirps, err := chirpResponses(r.Context(), &config, viewerID, chirps)
+			if err == nil {
+				retChirps, err = hideMutedWords(r.Context(), &config, viewerID, retChirps)
+			}
+			if err != nil {
+				log.Printf("error building /api/tags/{tag}/chirps response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			ret := response{Tag: tag.Name, Chirps: retChirps}
+			if len(chirps) == int(pageSize) {
+				last := chirps[len(chirps)-1]
+				ret.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+			}
+			dat, err := json.Marshal(ret)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/tags/trending",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type trendingTag struct {
+				Tag        string  `json:"tag"`
+				Score      float64 `json:"score"`
+				ChirpCount int32   `json:"chirp_count"`
+			}
+			type response struct {
+				ComputedAt *time.Time    `json:"computed_at"`
+				Tags       []trendingTag `json:"tags"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			trending, err := config.DbQueries.GetTrendingTags(r.Context())
+			if err != nil {
+				log.Printf("error getting trending tags: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			ret := response{Tags: make([]trendingTag, len(trending))}
+			for i, tag := range trending {
+				ret.Tags[i] = trendingTag{
+					Tag:        tag.Name,
+					Score:      tag.Score,
+					ChirpCount: tag.ChirpCount,
+				}
+				ret.ComputedAt = &tag.ComputedAt
+			}
+			dat, err := json.Marshal(ret)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users",
+		func(w http.ResponseWriter, r *http.Request) {
+			type parameters struct {
+				Email    string `json:"email"`
+				Password string `json:"password"`
+				Handle   string `json:"handle"`
+			}
+			type response struct {
+				ID          uuid.UUID `json:"id"`
+				CreatedAt   time.Time `json:"created_at"`
+				UpdatedAt   time.Time `json:"updated_at"`
+				Email       string    `json:"email"`
+				Handle      string    `json:"handle,omitempty"`
+				IsChirpyRed bool      `json:"is_chirpy_red"`
+			}
+			if r.Method == "POST" {
+				w.Header().Add("Content-Type", "application/json")
+				decoder := json.NewDecoder(r.Body)
+				params := parameters{}
+				err := decoder.Decode(&params)
+				email, emailParseErr := mail.ParseAddress(params.Email)
+				if emailParseErr != nil {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+				if params.Handle != "" {
+					if err := handles.Validate(params.Handle); err != nil {
+						marshal, _ := json.Marshal(utils.Error{
+							Error: err.Error(),
+						})
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(marshal)
+						return
+					}
+				}
+				hashed, err := auth.HashPassword(params.Password)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				user, createUserErr := config.DbQueries.CreateUser(
+					r.Context(),
+					database.CreateUserParams{
+						Email:          email.Address,
+						HashedPassword: hashed,
+						Handle:         sql.NullString{String: params.Handle, Valid: params.Handle != ""},
+					},
+				)
+				var pqErr *pq.Error
+				if errors.As(createUserErr, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_handle_idx" {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "handle is already taken",
+					})
+					w.WriteHeader(http.StatusConflict)
+					w.Write(marshal)
+					return
+				}
+				if createUserErr != nil {
+					return
+				}
+				if err != nil {
+					dat, err := json.Marshal(utils.Error{
+						Error: "error marshalling JSON: " + err.Error(),
+					})
+					if err != nil {
+						log.Printf("error writing /api/users response: %v", err)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(dat)
+					return
+				}
+				dat, err := json.Marshal(response{
+					ID:          user.ID,
+					CreatedAt:   user.CreatedAt,
+					UpdatedAt:   user.UpdatedAt,
+					Email:       user.Email,
+					Handle:      user.Handle.String,
+					IsChirpyRed: user.IsChirpyRed,
+				})
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write(dat)
+			} else if r.Method == "GET" {
+				// finds someone by email, only users who made themselves
+				// discoverable by email are found
+				w.Header().Set("Content-Type", "application/json")
+				bearerToken, err := auth.GetBearerToken(r.Header)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid authentication token",
+					})
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write(marshal)
+					return
+				}
+				userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid authentication token",
+					})
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write(marshal)
+					return
+				}
+				email, err := mail.ParseAddress(r.URL.Query().Get("email"))
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid email",
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				notFound := func() {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "user not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+				}
+				found, err := config.DbQueries.GetDiscoverableUserByEmail(r.Context(), email.Address)
+				if errors.Is(err, sql.ErrNoRows) {
+					notFound()
+					return
+				}
+				if err != nil {
+					log.Printf("error looking up user by email: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				// users who blocked each other look like they don't exist
+				blocked, err := config.DbQueries.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+					UserID:  userID,
+					OtherID: found.ID,
+				})
+				if err != nil {
+					log.Printf("error checking blocks between %s and %s: %v", userID, found.ID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if blocked || found.ShadowBanned {
+					notFound()
+					return
+				}
+				dat, err := json.Marshal(userSummary{
+					UserID: found.ID,
+					Handle: found.Handle.String,
+				})
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(dat)
+			} else if r.Method == "PUT" {
+				// every field is optional, only the ones sent are changed
+				type parameters struct {
+					Password    *string `json:"password"`
+					Email       *string `json:"email"`
+					Handle      *string `json:"handle"`
+					DisplayName *string `json:"display_name"`
+					Bio         *string `json:"bio"`
+					AvatarURL   *string `json:"avatar_url"`
+				}
+				type response struct {
+					ID          uuid.UUID `json:"id"`
+					CreatedAt   time.Time `json:"created_at"`
+					UpdatedAt   time.Time `json:"updated_at"`
+					Email       string    `json:"email"`
+					Handle      string    `json:"handle,omitempty"`
+					DisplayName string    `json:"display_name"`
+					Bio         string    `json:"bio"`
+					AvatarURL   string    `json:"avatar_url"`
+					IsChirpyRed bool      `json:"is_chirpy_red"`
+				}
+				bearerToken, err := auth.GetBearerToken(r.Header)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid authentication token",
+					})
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write(marshal)
+					return
+				}
+				userID, validateErr := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+				if validateErr != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "invalid authentication token",
+					})
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write(marshal)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				decoder := json.NewDecoder(r.Body)
+				params := parameters{}
+				decodeErr := decoder.Decode(&params)
+				if decodeErr != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				user, getUserErr := config.DbQueries.GetUserById(r.Context(), userID)
+				if getUserErr != nil {
+					log.Printf("error getting user %v: %v", userID, getUserErr)
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "user not found",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				invalid := func(msg string) {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: msg,
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+				}
+				credentials := database.UpdateUserByIDParams{
+					ID:             userID,
+					CreatedAt:      user.CreatedAt,
+					Email:          user.Email,
+					HashedPassword: user.HashedPassword,
+					IsChirpyRed:    user.IsChirpyRed,
+				}
+				if params.Email != nil {
+					email, emailParseErr := mail.ParseAddress(*params.Email)
+					if emailParseErr != nil {
+						invalid("invalid email")
+						return
+					}
+					credentials.Email = email.Address
+				}
+				if params.Password != nil {
+					hashedPassword, err := auth.HashPassword(*params.Password)
+					if err != nil {
+						invalid("invalid password")
+						return
+					}
+					credentials.HashedPassword = hashedPassword
+				}
+				profile := database.UpdateUserProfileParams{
+					ID:          userID,
+					Handle:      user.Handle,
+					DisplayName: user.DisplayName,
+					Bio:         user.Bio,
+					AvatarUrl:   user.AvatarUrl,
+				}
+				if params.Handle != nil {
+					if err := handles.Validate(*params.Handle); err != nil {
+						invalid(err.Error())
+						return
+					}
+					profile.Handle = sql.NullString{String: *params.Handle, Valid: true}
+				}
+				if params.DisplayName != nil {
+					if utf8.RuneCountInString(*params.DisplayName) > 50 {
+						invalid("display name is too long")
+						return
+					}
+					profile.DisplayName = strings.TrimSpace(*params.DisplayName)
+				}
+				if params.Bio != nil {
+					if utf8.RuneCountInString(*params.Bio) > 160 {
+						invalid("bio is too long")
+						return
+					}
+					profile.Bio = *params.Bio
+				}
+				if params.AvatarURL != nil {
+					if *params.AvatarURL != "" {
+						avatar, err := url.Parse(*params.AvatarURL)
+						if err != nil || (avatar.Scheme != "http" && avatar.Scheme != "https") || avatar.Host == "" || len(*params.AvatarURL) > 2048 {
+							invalid("avatar url must be an absolute http or https url")
+							return
+						}
+					}
+					profile.AvatarUrl = *params.AvatarURL
+				}
+
+				tx, err := db.BeginTx(r.Context(), nil)
+				if err != nil {
+					log.Printf("error starting user update transaction: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				defer tx.Rollback()
+				qtx := config.DbQueries.WithTx(tx)
+				_, updateUserErr := qtx.UpdateUserByID(r.Context(), credentials)
+				if updateUserErr != nil {
+					log.Printf("error updating user: %v", updateUserErr)
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				updatedUser, updateUserErr := qtx.UpdateUserProfile(r.Context(), profile)
+				var pqErr *pq.Error
+				if errors.As(updateUserErr, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_handle_idx" {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "handle is already taken",
+					})
+					w.WriteHeader(http.StatusConflict)
+					w.Write(marshal)
+					return
+				}
+				if updateUserErr != nil {
+					log.Printf("error updating user profile: %v", updateUserErr)
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				// old handles are kept so GET /api/users/{handle} can redirect
+				// links to them
+				if user.Handle.Valid && user.Handle.String != updatedUser.Handle.String {
+					err = qtx.CreateHandleHistory(r.Context(), database.CreateHandleHistoryParams{
+						UserID: userID,
+						Handle: user.Handle.String,
+					})
+					if err != nil {
+						log.Printf("error recording handle change of %s: %v", userID, err)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+				}
+				if err = tx.Commit(); err != nil {
+					log.Printf("error committing user update: %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				dat, err := json.Marshal(response{
+					ID:          updatedUser.ID,
+					CreatedAt:   updatedUser.CreatedAt,
+					UpdatedAt:   updatedUser.UpdatedAt,
+					Email:       updatedUser.Email,
+					Handle:      updatedUser.Handle.String,
+					DisplayName: updatedUser.DisplayName,
+					Bio:         updatedUser.Bio,
+					AvatarURL:   updatedUser.AvatarUrl,
+					IsChirpyRed: updatedUser.IsChirpyRed,
+				})
+				if err != nil {
+					log.Printf("error writing PUT /api/users response: %v", err)
+					return
+				}
+				w.Write(dat)
+				return
+			} else {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	go serveMux.HandleFunc(
+		"/api/users/{handle}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				ID             uuid.UUID `json:"id"`
+				CreatedAt      time.Time `json:"created_at"`
+				Handle         string    `json:"handle"`
+				DisplayName    string    `json:"display_name"`
+				Bio            string    `json:"bio"`
+				AvatarURL      string    `json:"avatar_url"`
+				IsChirpyRed    bool      `json:"is_chirpy_red"`
+				FollowersCount int64     `json:"followers_count"`
+				FollowingCount int64     `json:"following_count"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			handle := handles.Normalize(r.PathValue("handle"))
+			user, err := config.DbQueries.GetUserByHandle(r.Context(), handle)
+			if err != nil {
+				// a handle that was given up still leads to whoever had it,
+				// unless somebody else picked it since
+				previous, historyErr := config.DbQueries.GetLatestHandleHistory(r.Context(), handle)
+				if historyErr == nil {
+					current, err := config.DbQueries.GetUserById(r.Context(), previous.UserID)
+					if err == nil && current.Handle.Valid {
+						http.Redirect(w, r, "/api/users/"+url.PathEscape(current.Handle.String), http.StatusMovedPermanently)
+						return
+					}
+				}
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			followers, err := config.DbQueries.CountFollowers(r.Context(), user.ID)
+			if err != nil {
+				log.Printf("error counting followers of %s: %v", user.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			following, err := config.DbQueries.CountFollowing(r.Context(), user.ID)
+			if err != nil {
+				log.Printf("error counting users followed by %s: %v", user.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(response{
+				ID:             user.ID,
+				CreatedAt:      user.CreatedAt,
+				Handle:         user.Handle.String,
+				DisplayName:    user.DisplayName,
+				Bio:            user.Bio,
+				AvatarURL:      user.AvatarUrl,
+				IsChirpyRed:    user.IsChirpyRed,
+				FollowersCount: followers,
+				FollowingCount: following,
+			})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/{id}/follow",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			followeeID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid user id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if followeeID == userID {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "you cannot follow yourself",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			_, err = config.DbQueries.GetUserById(r.Context(), followeeID)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "POST" {
+				blocked, err := config.DbQueries.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+					UserID:  userID,
+					OtherID: followeeID,
+				})
+				if err != nil {
+					log.Printf("error checking blocks between %s and %s: %v", userID, followeeID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if blocked {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "you cannot follow this user",
+					})
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(marshal)
+					return
+				}
+			}
+
+			// the follow and its event are written together so consumers of
+			// follow_events never see an event for a change that was rolled back
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting follow transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			event := "follow"
+			var affected int64
+			if r.Method == "POST" {
+				affected, err = qtx.CreateFollow(r.Context(), database.CreateFollowParams{
+					FollowerID: userID,
+					FolloweeID: followeeID,
+				})
+			} else {
+				event = "unfollow"
+				affected, err = qtx.DeleteFollow(r.Context(), database.DeleteFollowParams{
+					FollowerID: userID,
+					FolloweeID: followeeID,
+				})
+			}
+			if err != nil {
+				log.Printf("error updating follow %s -> %s: %v", userID, followeeID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if affected == 0 {
+				if r.Method == "POST" {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "already following user",
+					})
+					w.WriteHeader(http.StatusConflict)
+					w.Write(marshal)
+					return
+				}
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "not following user",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			_, err = qtx.CreateFollowEvent(r.Context(), database.CreateFollowEventParams{
+				FollowerID: userID,
+				FolloweeID: followeeID,
+				Event:      event,
+			})
+			if err != nil {
+				log.Printf("error recording %s event: %v", event, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if r.Method == "POST" {
+				err = notify(r.Context(), qtx, database.CreateNotificationParams{
+					UserID:  followeeID,
+					ActorID: uuid.NullUUID{UUID: userID, Valid: true},
+					Type:    notifications.TypeFollow,
+				})
+				if err != nil {
+					log.Printf("error notifying %s of follow: %v", followeeID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			if err = tx.Commit(); err != nil {
+				log.Printf("error committing follow transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			follower, err := config.DbQueries.GetUserById(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting user %s: %v", userID, err)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if follower.PrecomputedTimeline {
+				if r.Method == "POST" {
+					err = precomputedTimeline.Follow(r.Context(), userID, followeeID)
+				} else {
+					err = precomputedTimeline.Unfollow(r.Context(), userID, followeeID)
+				}
+			} else if r.Method == "POST" {
+				following, countErr := config.DbQueries.CountFollowing(r.Context(), userID)
+				if countErr == nil && following >= precomputeThreshold {
+					err = precomputedTimeline.Enable(r.Context(), userID)
+				}
+			}
+			if err != nil {
+				log.Printf("error updating timeline of %s: %v", userID, err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/{id}/block",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			blockedID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid user id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if blockedID == userID {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "you cannot block yourself",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				affected, err := config.DbQueries.DeleteBlock(r.Context(), database.DeleteBlockParams{
+					BlockerID: userID,
+					BlockedID: blockedID,
+				})
+				if err != nil {
+					log.Printf("error unblocking %s -> %s: %v", userID, blockedID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if affected == 0 {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "not blocking user",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			_, err = config.DbQueries.GetUserById(r.Context(), blockedID)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+
+			// blocking ends the follows in both directions, with the
+			// unfollow events consumers of follow_events expect
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting block transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			affected, err := qtx.CreateBlock(r.Context(), database.CreateBlockParams{
+				BlockerID: userID,
+				BlockedID: blockedID,
+			})
+			if err != nil {
+				log.Printf("error blocking %s -> %s: %v", userID, blockedID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if affected == 0 {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "already blocking user",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			for _, follow := range []database.DeleteFollowParams{
+				{FollowerID: userID, FolloweeID: blockedID},
+				{FollowerID: blockedID, FolloweeID: userID},
+			} {
+				affected, err := qtx.DeleteFollow(r.Context(), follow)
+				if err == nil && affected > 0 {
+					_, err = qtx.CreateFollowEvent(r.Context(), database.CreateFollowEventParams{
+						FollowerID: follow.FollowerID,
+						FolloweeID: follow.FolloweeID,
+						Event:      "unfollow",
+					})
+				}
+				if err != nil {
+					log.Printf("error removing follow %s -> %s: %v", follow.FollowerID, follow.FolloweeID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			// neither can keep bookmarks of the other's chirps
+			err = qtx.DeleteBookmarksBetween(r.Context(), database.DeleteBookmarksBetweenParams{
+				UserID:  userID,
+				OtherID: blockedID,
+			})
+			if err != nil {
+				log.Printf("error deleting bookmarks between %s and %s: %v", userID, blockedID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err = tx.Commit(); err != nil {
+				log.Printf("error committing block transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := precomputedTimeline.Unfollow(r.Context(), userID, blockedID); err != nil {
+				log.Printf("error updating timeline of %s: %v", userID, err)
+			}
+			if err := precomputedTimeline.Unfollow(r.Context(), blockedID, userID); err != nil {
+				log.Printf("error updating timeline of %s: %v", blockedID, err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/{id}/mute",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			mutedID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid user id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if mutedID == userID {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "you cannot mute yourself",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				affected, err := config.DbQueries.DeleteMute(r.Context(), database.DeleteMuteParams{
+					MuterID: userID,
+					MutedID: mutedID,
+				})
+				if err != nil {
+					log.Printf("error unmuting %s -> %s: %v", userID, mutedID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if affected == 0 {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: "not muting user",
+					})
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(marshal)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			_, err = config.DbQueries.GetUserById(r.Context(), mutedID)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			// unlike a block, a mute is invisible to the muted user and
+			// leaves follows alone
+			affected, err := config.DbQueries.CreateMute(r.Context(), database.CreateMuteParams{
+				MuterID: userID,
+				MutedID: mutedID,
+			})
+			if err != nil {
+				log.Printf("error muting %s -> %s: %v", userID, mutedID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if affected == 0 {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "already muting user",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/me/blocks",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			blocks, err := config.DbQueries.GetBlocks(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting users blocked by %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			users := make([]relationResponse, len(blocks))
+			for i, block := range blocks {
+				users[i] = relationResponse{
+					UserID:    block.BlockedID,
+					CreatedAt: block.CreatedAt,
+				}
+			}
+			dat, err := json.Marshal(relationListResponse{Users: users})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/me/mutes",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			mutes, err := config.DbQueries.GetMutes(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting users muted by %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			users := make([]relationResponse, len(mutes))
+			for i, mute := range mutes {
+				users[i] = relationResponse{
+					UserID:    mute.MutedID,
+					CreatedAt: mute.CreatedAt,
+				}
+			}
+			dat, err := json.Marshal(relationListResponse{Users: users})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/me/muted-words",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "GET" {
+				words, err := config.DbQueries.GetActiveMutedWords(r.Context(), userID)
+				if err != nil {
+					log.Printf("error getting muted words of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				retWords := make([]mutedWordResponse, len(words))
+				for i, word := range words {
+					retWords[i] = mutedWordResponseFrom(word)
+				}
+				dat, err := json.Marshal(retWords)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(dat)
+				return
+			}
+			params, ok := decodeMutedWord(w, r)
+			if !ok {
+				return
+			}
+			// expired terms don't count towards the limit and can be
+			// muted again
+			if err := config.DbQueries.DeleteExpiredMutedWords(r.Context(), userID); err != nil {
+				log.Printf("error deleting expired muted words of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			count, err := config.DbQueries.CountActiveMutedWords(r.Context(), userID)
+			if err != nil {
+				log.Printf("error counting muted words of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if count >= mutedwords.MaxPerUser {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: fmt.Sprintf("you can mute at most %d terms", mutedwords.MaxPerUser),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			created, err := config.DbQueries.CreateMutedWord(r.Context(), database.CreateMutedWordParams{
+				UserID:    userID,
+				Term:      params.Term,
+				ExpiresAt: params.ExpiresAt,
+			})
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "this term is already muted",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error muting term for %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(mutedWordResponseFrom(created))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/me/muted-words/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "PUT" && r.Method != "DELETE" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			wordID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid muted word id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "DELETE" {
+				rows, err := config.DbQueries.DeleteMutedWord(r.Context(), database.DeleteMutedWordParams{
+					ID:     wordID,
+					UserID: userID,
+				})
+				if err != nil {
+					log.Printf("error deleting muted word %s: %v", wordID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if rows == 0 {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			params, ok := decodeMutedWord(w, r)
+			if !ok {
+				return
+			}
+			if err := config.DbQueries.DeleteExpiredMutedWords(r.Context(), userID); err != nil {
+				log.Printf("error deleting expired muted words of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			updated, err := config.DbQueries.UpdateMutedWord(r.Context(), database.UpdateMutedWordParams{
+				ID:        wordID,
+				UserID:    userID,
+				Term:      params.Term,
+				ExpiresAt: params.ExpiresAt,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "this term is already muted",
+				})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(marshal)
+				return
+			}
+			if err != nil {
+				log.Printf("error updating muted word %s: %v", wordID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(mutedWordResponseFrom(updated))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/{id}/followers",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			userID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid user id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			count, err := config.DbQueries.CountFollowers(r.Context(), userID)
+			if err != nil {
+				log.Printf("error counting followers of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			follows, err := config.DbQueries.GetFollowers(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting followers of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			users := make([]followResponse, len(follows))
+			for i, follow := range follows {
+				users[i] = followResponse{
+					UserID:     follow.FollowerID,
+					FollowedAt: follow.CreatedAt,
+				}
+			}
+			dat, err := json.Marshal(followListResponse{
+				Count: count,
+				Users: users,
+			})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/{id}/following",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			userID, err := uuid.Parse(r.PathValue("id"))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid user id",
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+				return
+			}
+			count, err := config.DbQueries.CountFollowing(r.Context(), userID)
+			if err != nil {
+				log.Printf("error counting users followed by %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			follows, err := config.DbQueries.GetFollowing(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting users followed by %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			users := make([]followResponse, len(follows))
+			for i, follow := range follows {
+				users[i] = followResponse{
+					UserID:     follow.FolloweeID,
+					FollowedAt: follow.CreatedAt,
+				}
+			}
+			dat, err := json.Marshal(followListResponse{
+				Count: count,
+				Users: users,
+			})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+
+	go serveMux.HandleFunc(
+		"/api/timeline",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Chirps     []chirpResponse `json:"chirps"`
+				NextCursor string          `json:"next_cursor,omitempty"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			pageSize := int64(timeline.DefaultPageSize)
+			if limit := r.URL.Query().Get("limit"); limit != "" {
+				pageSize, err = strconv.ParseInt(limit, 10, 32)
+				if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			}
+			var cursor *timeline.Cursor
+			if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+				parsed, err := timeline.ParseCursor(rawCursor)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				cursor = &parsed
+			}
+			user, err := config.DbQueries.GetUserById(r.Context(), userID)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			chirps, err := timeline.For(user, fanOutTimeline, precomputedTimeline).Page(
+				r.Context(),
+				userID,
+				cursor,
+				int32(pageSize),
+			)
+			if err != nil {
+				log.Printf("error getting timeline of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+			if err == nil {
+				retChirps, err = hideMutedWords(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, retChirps)
+			}
+			if err != nil {
+				log.Printf("error building /api/timeline response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			ret := response{Chirps: retChirps}
+			if len(chirps) == int(pageSize) {
+				last := chirps[len(chirps)-1]
+				ret.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+			}
+			dat, err := json.Marshal(ret)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+
+	go serveMux.HandleFunc(
+		"/api/users/me/settings",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "PATCH" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			// only the fields sent are changed. version is the settings
+			// version the client was written for, see settings.Version
+			type parameters struct {
+				Version       int `json:"version"`
+				Notifications *struct {
+					InApp map[string]bool `json:"in_app"`
+					Email map[string]bool `json:"email"`
+				} `json:"notifications"`
+				DiscoverableByEmail *bool   `json:"discoverable_by_email"`
+				MentionsFrom        *string `json:"mentions_from"`
+				MessagesFrom        *string `json:"messages_from"`
+				DefaultVisibility   *string `json:"default_visibility"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			if r.Method == "GET" {
+				current, err := settings.Load(r.Context(), config.DbQueries, userID)
+				if err != nil {
+					log.Printf("error getting settings of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				dat, err := json.Marshal(settingsResponseFrom(current))
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(dat)
+				return
+			}
+			invalid := func(err error) {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: err.Error(),
+				})
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write(marshal)
+			}
+			params := parameters{}
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&params); err != nil {
+				invalid(err)
+				return
+			}
+			if err := settings.CheckVersion(params.Version); err != nil {
+				invalid(err)
+				return
+			}
+			patch := settings.Patch{
+				DiscoverableByEmail: params.DiscoverableByEmail,
+				MentionsFrom:        params.MentionsFrom,
+				MessagesFrom:        params.MessagesFrom,
+				DefaultVisibility:   params.DefaultVisibility,
+			}
+			if params.Notifications != nil {
+				patch.InApp = params.Notifications.InApp
+				patch.Email = params.Notifications.Email
+			}
+			// the settings are locked from reading them to writing them
+			// back, so concurrent PATCHes each apply to the other's result
+			tx, err := db.BeginTx(r.Context(), nil)
+			if err != nil {
+				log.Printf("error starting settings transaction: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer tx.Rollback()
+			qtx := config.DbQueries.WithTx(tx)
+			current, err := settings.LoadForUpdate(r.Context(), qtx, userID)
+			if err != nil {
+				log.Printf("error getting settings of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			updated, err := patch.Apply(current)
+			if err != nil {
+				invalid(err)
+				return
+			}
+			row, err := qtx.UpsertUserSettings(r.Context(), database.UpsertUserSettingsParams{
+				UserID:              userID,
+				InAppDisabled:       updated.InAppDisabled,
+				EmailDisabled:       updated.EmailDisabled,
+				DiscoverableByEmail: updated.DiscoverableByEmail,
+				MentionsFrom:        updated.MentionsFrom,
+				MessagesFrom:        updated.MessagesFrom,
+				DefaultVisibility:   updated.DefaultVisibility,
+			})
+			if err != nil {
+				log.Printf("error updating settings of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				log.Printf("error committing settings of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			dat, err := json.Marshal(settingsResponseFrom(settings.From(row)))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/me/digest",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" && r.Method != "PUT" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type parameters struct {
+				Frequency *string `json:"frequency"`
+				Timezone  *string `json:"timezone"`
+			}
+			type response struct {
+				Frequency string `json:"frequency"`
+				Timezone  string `json:"timezone"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			user, err := config.DbQueries.GetUserById(r.Context(), userID)
+			if err != nil {
+				log.Printf("error getting user %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if r.Method == "PUT" {
+				// only the fields sent are changed
+				params := parameters{}
+				if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				update := database.UpdateUserDigestParams{
+					ID:              userID,
+					DigestFrequency: user.DigestFrequency,
+					Timezone:        user.Timezone,
+				}
+				invalid := func(err error) {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+				}
+				if params.Frequency != nil {
+					frequency, err := digest.ParseFrequency(*params.Frequency)
+					if err != nil {
+						invalid(err)
+						return
+					}
+					update.DigestFrequency = frequency
+				}
+				if params.Timezone != nil {
+					if _, err := digest.ParseTimezone(*params.Timezone); err != nil {
+						invalid(err)
+						return
+					}
+					update.Timezone = *params.Timezone
+				}
+				user, err = config.DbQueries.UpdateUserDigest(r.Context(), update)
+				if err != nil {
+					log.Printf("error updating digest of %s: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			dat, err := json.Marshal(response{
+				Frequency: user.DigestFrequency,
+				Timezone:  user.Timezone,
+			})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/digest/unsubscribe",
+		func(w http.ResponseWriter, r *http.Request) {
+			// GET is the link in the email and only asks to confirm, POST
+			// is the confirmation or the mail client's one-click
+			// unsubscribe from the List-Unsubscribe header
+			if r.Method != "GET" && r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			userID, err := uuid.Parse(r.URL.Query().Get("user_id"))
+			if err != nil || !digest.ValidUnsubscribeToken(config.JwtSecret, userID, r.URL.Query().Get("token")) {
+				w.Header().Set("Content-Type", "application/json")
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid unsubscribe link",
+				})
+				w.WriteHeader(http.StatusForbidden)
+				w.Write(marshal)
+				return
+			}
+			page := digest.UnsubscribePage{Action: r.URL.RequestURI()}
+			if r.Method == "POST" {
+				if _, err := config.DbQueries.UnsubscribeUserFromDigest(r.Context(), userID); err != nil {
+					log.Printf("error unsubscribing %s from digests: %v", userID, err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				page.Done = true
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := page.Render(w); err != nil {
+				log.Printf("error rendering unsubscribe page: %v", err)
+			}
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/users/me/mentions",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Chirps     []chirpResponse `json:"chirps"`
+				NextCursor string          `json:"next_cursor,omitempty"`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+			if err != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "invalid authentication token",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			pageSize := int64(timeline.DefaultPageSize)
+			if limit := r.URL.Query().Get("limit"); limit != "" {
+				pageSize, err = strconv.ParseInt(limit, 10, 32)
+				if err != nil || pageSize < 1 || pageSize > timeline.MaxPageSize {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: fmt.Sprintf("limit must be between 1 and %d", timeline.MaxPageSize),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+			}
+			params := database.RetrieveChirpsMentioningUserParams{
+				UserID:   userID,
+				PageSize: int32(pageSize),
+			}
+			if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+				cursor, err := timeline.ParseCursor(rawCursor)
+				if err != nil {
+					marshal, _ := json.Marshal(utils.Error{
+						Error: err.Error(),
+					})
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(marshal)
+					return
+				}
+				params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+				params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+			}
+			chirps, err := config.DbQueries.RetrieveChirpsMentioningUser(r.Context(), params)
+			if err != nil {
+				log.Printf("error getting mentions of %s: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			retChirps, err := chirpResponses(r.Context(), &config, uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+			if err != nil {
+				log.Printf("error building /api/users/me/mentions response: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			ret := response{Chirps: retChirps}
+			if len(chirps) == int(pageSize) {
+				last := chirps[len(chirps)-1]
+				ret.NextCursor = timeline.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+			}
+			dat, err := json.Marshal(ret)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(dat)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/login",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type parameters struct {
+				Email    string `json:"email"`
+				Password string `json:"password"`
+			}
+			type response struct {
+				ID           uuid.UUID `json:"id"`
+				CreatedAt    time.Time `json:"created_at"`
+				UpdatedAt    time.Time `json:"updated_at"`
+				Email        string    `json:"email"`
+				Token        string    `json:"token"`
+				RefreshToken string    `json:"refresh_token"`
+				IsChirpyRed  bool      `json:"is_chirpy_red"`
+			}
+			w.Header().Add("Content-Type", "application/json")
+			decoder := json.NewDecoder(r.Body)
+			params := parameters{}
+			err := decoder.Decode(&params)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			user, dbErr := config.DbQueries.GetUserByEmail(r.Context(), params.Email)
+			if dbErr != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			hashErr := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+			if hashErr != nil {
+				marshal, _ := json.Marshal(utils.Message{
+					Message: "Incorrect email or password",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+			if isSuspended(user) {
+				writeSuspended(w, user)
+				return
+			}
+			accessToken, JWTerr := auth.MakeJWT(user.ID, string(config.JwtSecret), time.Hour)
+			if JWTerr != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			refreshToken, err := auth.MakeRefreshToken()
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, err = config.DbQueries.CreateRefreshToken(
+				r.Context(),
+				database.CreateRefreshTokenParams{
+					Token:     refreshToken,
+					UserID:    user.ID,
+					ExpiresAt: time.Now().Add(24 * 60 * time.Hour),
+				},
+			)
+			if err != nil {
+				return
+			}
+			data, err := json.Marshal(response{
+				ID:           user.ID,
+				Email:        user.Email,
+				CreatedAt:    user.CreatedAt,
+				UpdatedAt:    user.UpdatedAt,
+				Token:        accessToken,
+				RefreshToken: refreshToken,
+				IsChirpyRed:  user.IsChirpyRed,
+			})
+			if err != nil {
+				return
+			}
+			w.Write(data)
+		},
+	)
+	go serveMux.HandleFunc(
+		"/api/refresh",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			type response struct {
+				Token string `json:"token"`
+			}
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			refreshToken, err := config.DbQueries.GetRefreshTokenByToken(
+				r.Context(),
+				bearerToken,
+			)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if refreshToken.RevokedAt.Valid {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if refreshToken.ExpiresAt.Before(time.Now()) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			user, err := config.DbQueries.GetUserById(r.Context(), refreshToken.UserID)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if isSuspended(user) {
+				w.Header().Set("Content-Type", "application/json")
+				writeSuspended(w, user)
+				return
+			}
+			accessToken, err := auth.MakeJWT(refreshToken.UserID, string(config.JwtSecret), time.Hour)
+			if err != nil {
+				return
+			}
+			marshal, err := json.Marshal(response{
+				Token: accessToken,
+			})
+			if err != nil {
+				return
+			}
+			w.Write(marshal)
+			return
+		},
+	)
+
+	go serveMux.HandleFunc(
+		"/api/revoke",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			bearerToken, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			refreshToken, err := config.DbQueries.GetRefreshTokenByToken(r.Context(), bearerToken)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			err = config.DbQueries.UpdateRefreshTokenByToken(
+				r.Context(),
+				database.UpdateRefreshTokenByTokenParams{
+					Token:     refreshToken.Token,
+					CreatedAt: refreshToken.CreatedAt,
+					UserID:    refreshToken.UserID,
+					ExpiresAt: time.Now().Add(24 * 60 * time.Hour),
+					RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+				},
+			)
+			if err != nil {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	go serveMux.HandleFunc("/api/polka/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Header().Set("Content-Type", "application/json")
+			type parameters struct {
+				Event string `json:"event"`
+				Data  struct {
+					UserID string `json:"user_id"`
+				} `json:"data"`
+			}
+			apiKey, apiKeyErr := auth.GetAPIKey(r.Header)
+			if apiKeyErr != nil {
+				marshal, _ := json.Marshal(utils.Message{
+					Message: "Invalid key",
+				})
+
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+
+			if apiKey != config.PolkaKey {
+				marshal, _ := json.Marshal(utils.Message{
+					Message: "Invalid key",
+				})
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write(marshal)
+				return
+			}
+
+			decoder := json.NewDecoder(r.Body)
+			params := parameters{}
+			err := decoder.Decode(&params)
+			if err != nil {
+				log.Printf("Failed decoding body params")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if params.Event != "user.upgraded" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			userID := params.Data.UserID
+			parsedUID, parseErr := uuid.Parse(userID)
+			if parseErr != nil {
+				log.Printf("Failed parsing userID %s", userID)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			user, getUserErr := config.DbQueries.GetUserById(r.Context(), parsedUID)
+			if getUserErr != nil {
+				marshal, _ := json.Marshal(utils.Error{
+					Error: "user not found",
+				})
+				log.Printf("Failed getting user %s", parsedUID)
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(marshal)
+				return
+			}
+			_, updateUserErr := config.DbQueries.UpdateUserByID(
+				r.Context(),
+				database.UpdateUserByIDParams{
+					ID:             parsedUID,
+					CreatedAt:      user.CreatedAt,
+					Email:          user.Email,
+					HashedPassword: user.HashedPassword,
+					IsChirpyRed:    true,
+				},
+			)
+			if updateUserErr != nil {
+				log.Printf("Failed updating user %s", user.Email)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			// Polka retries webhooks, the upgrade is only announced once
+			if !user.IsChirpyRed {
+				err := notify(r.Context(), config.DbQueries, database.CreateNotificationParams{
+					UserID: user.ID,
+					Type:   notifications.TypeChirpyRed,
+				})
+				if err != nil {
+					log.Printf("error notifying %s of upgrade: %v", user.ID, err)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+	})
+
+	go trendingRefresher.Run(context.Background())
+	go mediaWorker.Run(context.Background())
+	go chirpScheduler.Run(context.Background())
+	go digestSender.Run(context.Background())
+	if streamThroughPostgres {
+		go chirpStreamListener.Run(context.Background())
+	}
+	if moderationRulesFile == "" {
+		go moderationReloader.Run(context.Background())
+	}
+
+	go socketHub.Listen(context.Background(), dbUrl)
+
+	// on SIGINT or SIGTERM sockets are closed with 1001 going away, which
+	// http.Server.Shutdown doesn't do for hijacked connections, then the
+	// server drains
+	shutdownDone := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := socketHub.Shutdown(ctx); err != nil {
+			log.Printf("error closing sockets: %v", err)
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("error shutting down: %v", err)
+		}
+		close(shutdownDone)
+	}()
+
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdownDone
+		return
+	}
+	if err != nil {
+		_ = fmt.Errorf("server isn't starting")
+	}
+}
+
+type chirpResponse struct {
+	ID             uuid.UUID     `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Body           string        `json:"body"`
+	UserID         uuid.UUID     `json:"user_id"`
+	IsRechirp      bool          `json:"is_rechirp"`
+	Visibility     string        `json:"visibility"`
+	QuotedChirpID  *uuid.UUID    `json:"quoted_chirp_id,omitempty"`
+	QuotedChirp    *quotedChirp  `json:"quoted_chirp,omitempty"`
+	PublishAt      *time.Time    `json:"publish_at,omitempty"`
+	Poll           *pollResponse `json:"poll,omitempty"`
+	Pinned         bool          `json:"pinned"`
+	BookmarkedByMe bool          `json:"bookmarked_by_me"`
+	Mentions       []mention     `json:"mentions"`
+	Attachments    []attachment  `json:"attachments"`
+}
+
+// attachment is an image of a chirp. Until Status is "ready" the image is
+// still being processed and only its id is known; a "failed" image is never
+// shown.
+type attachment struct {
+	ID           uuid.UUID `json:"id"`
+	Status       string    `json:"status"`
+	URL          string    `json:"url,omitempty"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	SizeBytes    int64     `json:"size_bytes,omitempty"`
+	Width        int32     `json:"width,omitempty"`
+	Height       int32     `json:"height,omitempty"`
+	Blurhash     string    `json:"blurhash,omitempty"`
+}
+
+// mention is a resolved @handle, Start and End are byte offsets into the
+// chirp body.
+type mention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Start  int32     `json:"start"`
+	End    int32     `json:"end"`
+}
+
+// quotedChirp is the chirp embedded in a rechirp or a quote chirp. Once the
+// original is deleted, or when it's followers-only and the viewer doesn't
+// follow its author, only its id is left and Deleted is set.
+type quotedChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	Deleted   bool       `json:"deleted"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Body      string     `json:"body,omitempty"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// chirpResponses converts chirps into their API representation, embedding
+// the chirps they quote, their mentions and attachments with one extra query
+// each for the whole page.
+func chirpResponses(ctx context.Context, config *utils.ApiConfig, viewerID uuid.NullUUID, chirps []database.Chirp) ([]chirpResponse, error) {
+	queries := config.DbQueries
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	var quotedIDs []uuid.UUID
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+		if chirp.QuotedChirpID.Valid {
+			quotedIDs = append(quotedIDs, chirp.QuotedChirpID.UUID)
+		}
+	}
+	mentionRows, err := queries.RetrieveMentionsByChirpIds(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	mentioned := make(map[uuid.UUID][]mention)
+	for _, row := range mentionRows {
+		mentioned[row.ChirpID] = append(mentioned[row.ChirpID], mention{
+			UserID: row.UserID,
+			Handle: row.Handle.String,
+			Start:  row.StartOffset,
+			End:    row.EndOffset,
+		})
+	}
+	attachmentRows, err := queries.RetrieveAttachmentsByChirpIds(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	attached := make(map[uuid.UUID][]attachment)
+	for _, row := range attachmentRows {
+		retAttachment := attachment{ID: row.ID, Status: row.Status}
+		if row.Status == "ready" {
+			retAttachment.URL = config.Storage.URL(row.StorageKey)
+			retAttachment.ThumbnailURL = config.Storage.URL(row.ThumbnailKey)
+			retAttachment.ContentType = row.ContentType
+			retAttachment.SizeBytes = row.SizeBytes
+			retAttachment.Width = row.Width
+			retAttachment.Height = row.Height
+			retAttachment.Blurhash = row.Blurhash
+		}
+		attached[row.ChirpID] = append(attached[row.ChirpID], retAttachment)
+	}
+	pinnedIDs, err := queries.RetrievePinnedAmongChirpIds(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[uuid.UUID]bool, len(pinnedIDs))
+	for _, id := range pinnedIDs {
+		pinned[id] = true
+	}
+	bookmarked := make(map[uuid.UUID]bool)
+	if viewerID.Valid {
+		bookmarkedIDs, err := queries.RetrieveBookmarkedAmongChirpIds(ctx, database.RetrieveBookmarkedAmongChirpIdsParams{
+			UserID:   viewerID.UUID,
+			ChirpIds: chirpIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range bookmarkedIDs {
+			bookmarked[id] = true
+		}
+	}
+	chirpPolls, err := queries.RetrievePollsByChirpIds(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	polled, err := pollResponses(ctx, queries, viewerID, chirpPolls)
+	if err != nil {
+		return nil, err
+	}
+	quoted := make(map[uuid.UUID]database.Chirp, len(quotedIDs))
+	if len(quotedIDs) > 0 {
+		found, err := queries.RetrieveChirpsByIds(ctx, database.RetrieveChirpsByIdsParams{
+			Ids:      quotedIDs,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, chirp := range found {
+			quoted[chirp.ID] = chirp
+		}
+	}
+	retChirps := make([]chirpResponse, len(chirps))
+	for i, chirp := range chirps {
+		retChirps[i] = chirpResponse{
+			ID:             chirp.ID,
+			CreatedAt:      chirp.CreatedAt,
+			UpdatedAt:      chirp.UpdatedAt,
+			Body:           chirp.Body,
+			UserID:         chirp.UserID,
+			IsRechirp:      chirp.IsRechirp,
+			Visibility:     chirp.Visibility,
+			Mentions:       mentioned[chirp.ID],
+			Pinned:         pinned[chirp.ID],
+			BookmarkedByMe: bookmarked[chirp.ID],
+		}
+		if retChirps[i].Mentions == nil {
+			retChirps[i].Mentions = []mention{}
+		}
+		retChirps[i].Attachments = attached[chirp.ID]
+		if retChirps[i].Attachments == nil {
+			retChirps[i].Attachments = []attachment{}
+		}
+		if chirp.PublishAt.Valid {
+			publishAt := chirp.PublishAt.Time
+			retChirps[i].PublishAt = &publishAt
+		}
+		if poll, ok := polled[chirp.ID]; ok {
+			retChirps[i].Poll = &poll
+		}
+		if !chirp.QuotedChirpID.Valid {
+			continue
+		}
+		quotedID := chirp.QuotedChirpID.UUID
+		retChirps[i].QuotedChirpID = &quotedID
+		original, ok := quoted[quotedID]
+		if !ok {
+			retChirps[i].QuotedChirp = &quotedChirp{ID: quotedID, Deleted: true}
+			continue
+		}
+		retChirps[i].QuotedChirp = &quotedChirp{
+			ID:        original.ID,
+			CreatedAt: &original.CreatedAt,
+			UpdatedAt: &original.UpdatedAt,
+			Body:      original.Body,
+			UserID:    &original.UserID,
+		}
+	}
+	return retChirps, nil
+}
+
+type followResponse struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followListResponse struct {
+	Count int64            `json:"count"`
+	Users []followResponse `json:"users"`
+}
+
+// relationResponse is a user the caller blocked or muted, and since when.
+type relationResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type relationListResponse struct {
+	Users []relationResponse `json:"users"`
+}
+
+// storeChirpTags links a chirp to the hashtags in its body, creating tags
+// that haven't been used before.
+func storeChirpTags(ctx context.Context, queries *database.Queries, chirp database.Chirp) error {
+	for _, name := range hashtags.Extract(chirp.Body) {
+		tag, err := queries.UpsertTag(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = queries.CreateChirpTag(ctx, database.CreateChirpTagParams{
+			ChirpID:   chirp.ID,
+			TagID:     tag.ID,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeChirpPoll stores a poll attached to a new chirp, with its options in
+// the order they were given.
+func storeChirpPoll(ctx context.Context, queries *database.Queries, chirp database.Chirp, poll newPoll) error {
+	created, err := queries.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirp.ID,
+		ClosesAt: poll.ClosesAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	for i, option := range poll.Options {
+		_, err := queries.CreatePollOption(ctx, database.CreatePollOptionParams{
+			PollID:   created.ID,
+			Position: int32(i),
+			Text:     strings.TrimSpace(option),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeChirpMentions resolves the @handles in a chirp body against users,
+// mentions of handles nobody has are left as plain text.
+func storeChirpMentions(ctx context.Context, queries *database.Queries, chirp database.Chirp) error {
+	found := mentions.Extract(chirp.Body)
+	if len(found) == 0 {
+		return nil
+	}
+	names := make([]string, len(found))
+	for i, m := range found {
+		names[i] = handles.Normalize(m.Handle)
+	}
+	users, err := queries.GetUsersByHandles(ctx, names)
+	if err != nil {
+		return err
+	}
+	byHandle := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		byHandle[handles.Normalize(user.Handle.String)] = user.ID
+	}
+	notified := make(map[uuid.UUID]bool)
+	// users who only take mentions from some people aren't mentioned by
+	// the others, the chirp is posted without linking them
+	allowed := make(map[uuid.UUID]bool)
+	for _, user := range users {
+		mentionedSettings, err := settings.Load(ctx, queries, user.ID)
+		if err != nil {
+			return err
+		}
+		allowed[user.ID], err = reaches(ctx, queries, mentionedSettings.MentionsFrom, user.ID, chirp.UserID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, m := range found {
+		userID, ok := byHandle[handles.Normalize(m.Handle)]
+		if !ok || !allowed[userID] {
+			continue
+		}
+		err := queries.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID:     chirp.ID,
+			UserID:      userID,
+			StartOffset: int32(m.Start),
+			EndOffset:   int32(m.End),
+			CreatedAt:   chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		// users mentioned in a followers-only chirp they can't see aren't
+		// told about it
+		if notified[userID] || !chirpVisibleTo(ctx, queries, chirp, uuid.NullUUID{UUID: userID, Valid: true}) {
+			continue
+		}
+		notified[userID] = true
+		err = notify(ctx, queries, database.CreateNotificationParams{
+			UserID:  userID,
+			ActorID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+			Type:    notifications.TypeMention,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeChirpAttachments uploads a chirp's images as they were sent and
+// queues them, in order, for the media worker. The worker writes the
+// processed image to the attachment's storage key.
+func storeChirpAttachments(ctx context.Context, config *utils.ApiConfig, chirp database.Chirp, images []media.Image) error {
+	for i, image := range images {
+		id := uuid.New()
+		original := fmt.Sprintf("incoming/%s/%s%s", chirp.ID, id, image.Extension)
+		err := config.Storage.Put(ctx, original, bytes.NewReader(image.Data), image.ContentType)
+		if err != nil {
+			return err
+		}
+		_, err = config.DbQueries.CreateChirpAttachment(ctx, database.CreateChirpAttachmentParams{
+			ID:          id,
+			ChirpID:     chirp.ID,
+			Position:    int32(i),
+			StorageKey:  fmt.Sprintf("chirps/%s/%s%s", chirp.ID, id, image.Extension),
+			OriginalKey: sql.NullString{String: original, Valid: true},
+			ContentType: image.ContentType,
+			SizeBytes:   int64(len(image.Data)),
+		})
+		if err != nil {
+			config.Storage.Delete(ctx, original)
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteChirp removes a chirp along with the files of its attachments.
+func deleteChirp(ctx context.Context, config *utils.ApiConfig, chirpID uuid.UUID) error {
+	attachments, err := config.DbQueries.RetrieveAttachmentsByChirpIds(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		return err
+	}
+	if err := config.DbQueries.DeleteChirpById(ctx, chirpID); err != nil {
+		return err
+	}
+	deleteAttachmentFiles(ctx, config, attachments)
+	return nil
+}
+
+// deleteAttachmentFiles removes the stored files of attachments whose
+// chirp is gone.
+func deleteAttachmentFiles(ctx context.Context, config *utils.ApiConfig, attachments []database.ChirpAttachment) {
+	for _, attachment := range attachments {
+		keys := []string{attachment.StorageKey, attachment.ThumbnailKey, attachment.OriginalKey.String}
+		for _, key := range keys {
+			if key == "" {
+				continue
+			}
+			if err := config.Storage.Delete(ctx, key); err != nil {
+				log.Printf("error deleting %s: %v", key, err)
+			}
+		}
+	}
+}
+
+type moderationRuleResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Term      string    `json:"term"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func moderationRuleResponseFrom(rule database.ModerationRule) moderationRuleResponse {
+	return moderationRuleResponse{
+		ID:        rule.ID,
+		Term:      rule.Term,
+		Action:    rule.Action,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+	}
+}
+
+// chirpFlagResponse is a chirp that matched a flag rule. RuleID is missing
+// for rules loaded from a file.
+type chirpFlagResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	ChirpID   uuid.UUID  `json:"chirp_id"`
+	RuleID    *uuid.UUID `json:"rule_id,omitempty"`
+	Term      string     `json:"term"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// authenticateModerator checks that a request carries the token of a
+// moderator. When it doesn't, the error response has already been written.
+func authenticateModerator(w http.ResponseWriter, r *http.Request, config *utils.ApiConfig) (database.User, bool) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "invalid authentication token",
+		})
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write(marshal)
+		return database.User{}, false
+	}
+	userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+	if err != nil {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "invalid authentication token",
+		})
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write(marshal)
+		return database.User{}, false
+	}
+	user, err := config.DbQueries.GetUserById(r.Context(), userID)
+	if err != nil {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "invalid authentication token",
+		})
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write(marshal)
+		return database.User{}, false
+	}
+	if !user.IsModerator {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "moderator access required",
+		})
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(marshal)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// storeChirpFlags records the flag rules a chirp matched, once per rule, so
+// moderators can review it.
+func storeChirpFlags(ctx context.Context, queries *database.Queries, chirp database.Chirp, moderated moderation.Result) error {
+	flagged := make(map[string]bool)
+	for _, match := range moderated.Matches {
+		if match.Rule.Action != moderation.ActionFlag || flagged[match.Rule.Term] {
+			continue
+		}
+		flagged[match.Rule.Term] = true
+		err := queries.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+			ChirpID: chirp.ID,
+			RuleID:  uuid.NullUUID{UUID: match.Rule.ID, Valid: match.Rule.ID != uuid.Nil},
+			Term:    match.Rule.Term,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportResponse is a report as shown to moderators. Reporters see their own
+// reports without AssigneeID and ResolvedBy.
+type reportResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	ChirpID    *uuid.UUID `json:"chirp_id,omitempty"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	Outcome    string     `json:"outcome,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	AssigneeID *uuid.UUID `json:"assignee_id,omitempty"`
+	ResolvedBy *uuid.UUID `json:"resolved_by,omitempty"`
+}
+
+func reportResponseFrom(report database.Report, forModerator bool) reportResponse {
+	resp := reportResponse{
+		ID:         report.ID,
+		CreatedAt:  report.CreatedAt,
+		UpdatedAt:  report.UpdatedAt,
+		ReporterID: report.ReporterID,
+		UserID:     report.UserID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Status:     report.Status,
+		Outcome:    report.Outcome.String,
+	}
+	if report.ChirpID.Valid {
+		resp.ChirpID = &report.ChirpID.UUID
+	}
+	if report.ResolvedAt.Valid {
+		resp.ResolvedAt = &report.ResolvedAt.Time
+	}
+	if !forModerator {
+		return resp
+	}
+	if report.AssigneeID.Valid {
+		resp.AssigneeID = &report.AssigneeID.UUID
+	}
+	if report.ResolvedBy.Valid {
+		resp.ResolvedBy = &report.ResolvedBy.UUID
+	}
+	return resp
+}
+
+// userRestrictions is what moderators see of a user's suspension and
+// shadow-ban.
+type userRestrictions struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	ShadowBanned     bool       `json:"shadow_banned"`
+}
+
+func userRestrictionsFrom(user database.User) userRestrictions {
+	restrictions := userRestrictions{
+		UserID:       user.ID,
+		ShadowBanned: user.ShadowBanned,
+	}
+	if isSuspended(user) {
+		restrictions.SuspendedUntil = &user.SuspendedUntil.Time
+		restrictions.SuspensionReason = user.SuspensionReason
+	}
+	return restrictions
+}
+
+func isSuspended(user database.User) bool {
+	return user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(time.Now())
+}
+
+// writeSuspended refuses a suspended user a token, telling them why and for
+// how long.
+func writeSuspended(w http.ResponseWriter, user database.User) {
+	type response struct {
+		Error          string    `json:"error"`
+		Reason         string    `json:"reason"`
+		SuspendedUntil time.Time `json:"suspended_until"`
+	}
+	marshal, _ := json.Marshal(response{
+		Error:          "account suspended",
+		Reason:         user.SuspensionReason,
+		SuspendedUntil: user.SuspendedUntil.Time,
+	})
+	w.WriteHeader(http.StatusForbidden)
+	w.Write(marshal)
+}
+
+// suspensionReason is the reason shown to a user suspended through a report,
+// the moderator's note when there is one.
+func suspensionReason(reportReason, note string) string {
+	if note != "" {
+		return note
+	}
+	return "reported for " + reportReason
+}
+
+// optionalViewer returns the user a request is authenticated as, on routes
+// that work without authentication too. A missing or invalid token makes an
+// anonymous viewer.
+func optionalViewer(r *http.Request, config *utils.ApiConfig) uuid.NullUUID {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := auth.ValidateJWT(bearerToken, string(config.JwtSecret))
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+type mutedWordResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Term      string     `json:"term"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func mutedWordResponseFrom(word database.MutedWord) mutedWordResponse {
+	ret := mutedWordResponse{
+		ID:        word.ID,
+		Term:      word.Term,
+		CreatedAt: word.CreatedAt,
+		UpdatedAt: word.UpdatedAt,
+	}
+	if word.ExpiresAt.Valid {
+		ret.ExpiresAt = &word.ExpiresAt.Time
+	}
+	return ret
+}
+
+// mutedWordParams is the body of creating or replacing a muted word. Without
+// expires_at the term stays muted until it's deleted.
+type mutedWordParams struct {
+	Term      string
+	ExpiresAt sql.NullTime
+}
+
+// decodeMutedWord reads and validates a muted word from the request body,
+// writing a 400 and returning false if it isn't valid.
+func decodeMutedWord(w http.ResponseWriter, r *http.Request) (mutedWordParams, bool) {
+	type parameters struct {
+		Term      string     `json:"term"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "error marshalling JSON: " + err.Error(),
+		})
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(marshal)
+		return mutedWordParams{}, false
+	}
+	ret := mutedWordParams{Term: strings.TrimSpace(params.Term)}
+	if err := mutedwords.Validate(ret.Term); err != nil {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: err.Error(),
+		})
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(marshal)
+		return mutedWordParams{}, false
+	}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			marshal, _ := json.Marshal(utils.Error{
+				Error: "expires_at must be in the future",
+			})
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(marshal)
+			return mutedWordParams{}, false
+		}
+		ret.ExpiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+	return ret, true
+}
+
+// hideMutedWords drops the chirps containing one of the viewer's muted
+// terms, or quoting a chirp that does. The terms are read once and matched
+// in memory, so a page costs a single query. A filtered page can come back
+// shorter than its limit, its next_cursor still follows the unfiltered rows.
+// The viewer's own chirps are always kept.
+func hideMutedWords(ctx context.Context, config *utils.ApiConfig, viewerID uuid.NullUUID, chirps []chirpResponse) ([]chirpResponse, error) {
+	if !viewerID.Valid || len(chirps) == 0 {
+		return chirps, nil
+	}
+	words, err := config.DbQueries.GetActiveMutedWords(ctx, viewerID.UUID)
+	if err != nil {
+		return nil, err
+	}
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = word.Term
+	}
+	matcher := mutedwords.NewMatcher(terms)
+	if matcher.Empty() {
+		return chirps, nil
+	}
+	kept := make([]chirpResponse, 0, len(chirps))
+	for _, chirp := range chirps {
+		if chirp.UserID != viewerID.UUID {
+			if matcher.Matches(chirp.Body) {
+				continue
+			}
+			if chirp.QuotedChirp != nil && matcher.Matches(chirp.QuotedChirp.Body) {
+				continue
+			}
+		}
+		kept = append(kept, chirp)
+	}
+	return kept, nil
+}
+
+type draftResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Body          string     `json:"body"`
+	QuotedChirpID *uuid.UUID `json:"quoted_chirp_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func draftResponseFrom(draft database.Draft) draftResponse {
+	ret := draftResponse{
+		ID:        draft.ID,
+		Body:      draft.Body,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+	}
+	if draft.QuotedChirpID.Valid {
+		ret.QuotedChirpID = &draft.QuotedChirpID.UUID
+	}
+	return ret
+}
+
+// draftParams is the body of saving a draft. The quoted chirp is only
+// looked up when the draft is published, it may be gone by then.
+type draftParams struct {
+	Body          string
+	QuotedChirpID uuid.NullUUID
+}
+
+// decodeDraft reads and validates a draft from the request body, writing a
+// 400 and returning false if it isn't valid.
+func decodeDraft(w http.ResponseWriter, r *http.Request) (draftParams, bool) {
+	type parameters struct {
+		Body          string     `json:"body"`
+		QuotedChirpID *uuid.UUID `json:"quoted_chirp_id"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "error marshalling JSON: " + err.Error(),
+		})
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(marshal)
+		return draftParams{}, false
+	}
+	if err := drafts.Validate(params.Body, params.QuotedChirpID != nil); err != nil {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: err.Error(),
+		})
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(marshal)
+		return draftParams{}, false
+	}
+	ret := draftParams{Body: params.Body}
+	if params.QuotedChirpID != nil {
+		ret.QuotedChirpID = uuid.NullUUID{UUID: *params.QuotedChirpID, Valid: true}
+	}
+	return ret, true
+}
+
+// newChirp is what an author writes in a chirp, as posted or saved in a
+// draft.
+type newChirp struct {
+	Body          string
+	QuotedChirpID *uuid.UUID
+	PublishAt     *time.Time
+	Poll          *newPoll
+	Visibility    string
+}
+
+type newPoll struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+// requestError is an error in what a client sent, written back to it with
+// Status.
+type requestError struct {
+	Status  int
+	Message string
+}
+
+func (e *requestError) Error() string {
+	return e.Message
+}
+
+// writeRequestError writes err as a *requestError, or logs it after
+// logPrefix and writes a 500.
+func writeRequestError(w http.ResponseWriter, err error, logPrefix string) {
+	var reqErr *requestError
+	if !errors.As(err, &reqErr) {
+		log.Printf("%s: %v", logPrefix, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	marshal, _ := json.Marshal(utils.Error{
+		Error: reqErr.Message,
+	})
+	w.WriteHeader(reqErr.Status)
+	w.Write(marshal)
+}
+
+// visiblePoll looks up a poll the way its chirp is looked up for viewerID,
+// writing a 404 and returning false when the chirp is scheduled, by a
+// shadow-banned author or blocked either way.
+func visiblePoll(w http.ResponseWriter, r *http.Request, config *utils.ApiConfig, pollID uuid.UUID, viewerID uuid.NullUUID) (database.Poll, bool) {
+	notFound := func() (database.Poll, bool) {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "poll not found",
+		})
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(marshal)
+		return database.Poll{}, false
+	}
+	poll, err := config.DbQueries.GetPollById(r.Context(), pollID)
+	if err != nil {
+		return notFound()
+	}
+	chirp, err := config.DbQueries.RetrieveChirpById(r.Context(), poll.ChirpID)
+	if err != nil || !chirpVisibleTo(r.Context(), config.DbQueries, chirp, viewerID) {
+		return notFound()
+	}
+	return poll, true
+}
+
+type bookmarkResponse struct {
+	ChirpID      uuid.UUID  `json:"chirp_id"`
+	CollectionID *uuid.UUID `json:"collection_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func bookmarkResponseFrom(bookmark database.Bookmark) bookmarkResponse {
+	ret := bookmarkResponse{
+		ChirpID:   bookmark.ChirpID,
+		CreatedAt: bookmark.CreatedAt,
+	}
+	if bookmark.CollectionID.Valid {
+		ret.CollectionID = &bookmark.CollectionID.UUID
+	}
+	return ret
+}
+
+type bookmarkCollectionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func bookmarkCollectionResponseFrom(collection database.BookmarkCollection) bookmarkCollectionResponse {
+	return bookmarkCollectionResponse{
+		ID:        collection.ID,
+		Name:      collection.Name,
+		CreatedAt: collection.CreatedAt,
+		UpdatedAt: collection.UpdatedAt,
+	}
+}
+
+// chirpVisibleTo reports whether viewerID can see a published chirp: its
+// author isn't shadow-banned, unless they're the viewer, there's no block
+// between them either way, and a followers-only chirp is seen by a follower.
+// Unlisted chirps are visible, they're only left out of listings.
+func chirpVisibleTo(ctx context.Context, queries *database.Queries, chirp database.Chirp, viewerID uuid.NullUUID) bool {
+	author, err := queries.GetUserById(ctx, chirp.UserID)
+	if err != nil || (author.ShadowBanned && viewerID.UUID != author.ID) {
+		return false
+	}
+	if !viewerID.Valid {
+		return visibility.Reachable(chirp.Visibility, false, false)
+	}
+	blocked, err := queries.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{
+		UserID:  viewerID.UUID,
+		OtherID: author.ID,
+	})
+	if err != nil || blocked {
+		return false
+	}
+	if visibility.Reachable(chirp.Visibility, viewerID.UUID == author.ID, false) {
+		return true
+	}
+	following, err := queries.IsFollowing(ctx, database.IsFollowingParams{
+		FollowerID: viewerID.UUID,
+		FolloweeID: author.ID,
+	})
+	return err == nil && following
+}
+
+// reaches reports whether sender can reach recipient given recipient's
+// audience setting for mentions or messages.
+func reaches(ctx context.Context, queries *database.Queries, audience string, recipientID, senderID uuid.UUID) (bool, error) {
+	isSelf := recipientID == senderID
+	isFollowed := false
+	if audience == settings.Following && !isSelf {
+		var err error
+		isFollowed, err = queries.IsFollowing(ctx, database.IsFollowingParams{
+			FollowerID: recipientID,
+			FolloweeID: senderID,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return settings.Allows(audience, isSelf, isFollowed), nil
+}
+
+// settingsResponse is the settings resource, notification types map to
+// whether they're on.
+type settingsResponse struct {
+	Version             int                          `json:"version"`
+	Notifications       notificationSettingsResponse `json:"notifications"`
+	DiscoverableByEmail bool                         `json:"discoverable_by_email"`
+	MentionsFrom        string                       `json:"mentions_from"`
+	MessagesFrom        string                       `json:"messages_from"`
+	DefaultVisibility   string                       `json:"default_visibility"`
+}
+
+type notificationSettingsResponse struct {
+	InApp map[string]bool `json:"in_app"`
+	Email map[string]bool `json:"email"`
+}
+
+func settingsResponseFrom(s settings.Settings) settingsResponse {
+	ret := settingsResponse{
+		Version: settings.Version,
+		Notifications: notificationSettingsResponse{
+			InApp: make(map[string]bool, len(settings.InAppTypes)),
+			Email: make(map[string]bool, len(settings.EmailTypes)),
+		},
+		DiscoverableByEmail: s.DiscoverableByEmail,
+		MentionsFrom:        s.MentionsFrom,
+		MessagesFrom:        s.MessagesFrom,
+		DefaultVisibility:   s.DefaultVisibility,
+	}
+	for _, notificationType := range settings.InAppTypes {
+		ret.Notifications.InApp[notificationType] = s.InApp(notificationType)
+	}
+	for _, notificationType := range settings.EmailTypes {
+		ret.Notifications.Email[notificationType] = s.Email(notificationType)
+	}
+	return ret
+}
+
+// userSummary is a user listed with something they took part in.
+type userSummary struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle,omitempty"`
+}
+
+// conversationResponse is a conversation as one of its participants sees
+// it, UnreadCount is how many messages others sent since they last read it.
+type conversationResponse struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	LastMessageAt time.Time     `json:"last_message_at"`
+	UnreadCount   int64         `json:"unread_count"`
+	Participants  []userSummary `json:"participants"`
+}
+
+// conversationResponses converts conversations into their API
+// representation, with the participants of the whole page in one query.
+func conversationResponses(ctx context.Context, queries *database.Queries, conversations []database.RetrieveConversationsRow) ([]conversationResponse, error) {
+	conversationIDs := make([]uuid.UUID, len(conversations))
+	for i, conversation := range conversations {
+		conversationIDs[i] = conversation.ID
+	}
+	participantRows, err := queries.RetrieveParticipantsByConversationIds(ctx, conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+	participants := make(map[uuid.UUID][]userSummary)
+	for _, row := range participantRows {
+		participants[row.ConversationID] = append(participants[row.ConversationID], userSummary{
+			UserID: row.UserID,
+			Handle: row.Handle.String,
+		})
+	}
+	ret := make([]conversationResponse, len(conversations))
+	for i, conversation := range conversations {
+		ret[i] = conversationResponse{
+			ID:            conversation.ID,
+			CreatedAt:     conversation.CreatedAt,
+			UpdatedAt:     conversation.UpdatedAt,
+			LastMessageAt: conversation.LastMessageAt,
+			UnreadCount:   conversation.UnreadCount,
+			Participants:  participants[conversation.ID],
+		}
+	}
+	return ret, nil
+}
+
+type messageResponse struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func messageResponseFrom(message database.Message) messageResponse {
+	return messageResponse{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+		CreatedAt:      message.CreatedAt,
+	}
+}
+
+// checkMessage validates a message body and runs it through the moderation
+// filter chirps go through, returning it masked. Flag rules don't apply,
+// moderators don't read private messages.
+func checkMessage(filter *moderation.Filter, body string) (string, error) {
+	if err := messages.Validate(body); err != nil {
+		return "", &requestError{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+	moderated := filter.Check(body)
+	if moderated.Rejected() {
+		return "", &requestError{Status: http.StatusBadRequest, Message: "Message contains prohibited content"}
+	}
+	return moderated.Body, nil
+}
+
+// sendMessage stores a message and moves its conversation to the top of its
+// participants' lists.
+func sendMessage(ctx context.Context, queries *database.Queries, conversationID, senderID uuid.UUID, body string) (database.Message, error) {
+	message, err := queries.CreateMessage(ctx, database.CreateMessageParams{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Body:           body,
+	})
+	if err != nil {
+		return database.Message{}, err
+	}
+	err = queries.SetConversationLastMessage(ctx, database.SetConversationLastMessageParams{
+		ID:            conversationID,
+		LastMessageAt: message.CreatedAt,
+	})
+	return message, err
+}
+
+// participantConversation gets the conversation in the request path for
+// userID, writing a 404 when they're not part of it.
+func participantConversation(w http.ResponseWriter, r *http.Request, config *utils.ApiConfig, userID uuid.UUID) (database.GetConversationForParticipantRow, bool) {
+	notFound := func() (database.GetConversationForParticipantRow, bool) {
+		marshal, _ := json.Marshal(utils.Error{
+			Error: "conversation not found",
+		})
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(marshal)
+		return database.GetConversationForParticipantRow{}, false
+	}
+	conversationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return notFound()
+	}
+	conversation, err := config.DbQueries.GetConversationForParticipant(r.Context(), database.GetConversationForParticipantParams{
+		UserID: userID,
+		ID:     conversationID,
+	})
+	if err != nil {
+		return notFound()
+	}
+	return conversation, true
+}
+
+// notificationResponse is a group of notifications shown together, or a
+// single one. ID marks them all read.
+type notificationResponse struct {
+	ID          string        `json:"id"`
+	Type        string        `json:"type"`
+	Summary     string        `json:"summary"`
+	ChirpID     *uuid.UUID    `json:"chirp_id,omitempty"`
+	ReportID    *uuid.UUID    `json:"report_id,omitempty"`
+	Count       int64         `json:"count"`
+	UnreadCount int64         `json:"unread_count"`
+	Actors      []userSummary `json:"actors"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// notificationResponses converts groups of notifications into their API
+// representation, with the latest actors of the whole page in one query.
+func notificationResponses(ctx context.Context, queries *database.Queries, userID uuid.UUID, groups []database.RetrieveNotificationGroupsRow) ([]notificationResponse, error) {
+	groupIDs := make([]string, len(groups))
+	for i, group := range groups {
+		groupIDs[i] = group.GroupID
+	}
+	actorRows, err := queries.RetrieveNotificationActors(ctx, database.RetrieveNotificationActorsParams{
+		UserID:   userID,
+		GroupIds: groupIDs,
+		PerGroup: notifications.MaxActors,
+	})
+	if err != nil {
+		return nil, err
+	}
+	actors := make(map[string][]userSummary)
+	for _, row := range actorRows {
+		actors[row.GroupID] = append(actors[row.GroupID], userSummary{
+			UserID: row.ActorID.UUID,
+			Handle: row.Handle.String,
+		})
+	}
+	ret := make([]notificationResponse, len(groups))
+	for i, group := range groups {
+		ret[i] = notificationResponse{
+			ID:          group.GroupID,
+			Type:        group.Type,
+			Count:       group.Count,
+			UnreadCount: group.UnreadCount,
+			Actors:      actors[group.GroupID],
+			CreatedAt:   group.LatestAt,
+		}
+		if ret[i].Actors == nil {
+			ret[i].Actors = []userSummary{}
+		}
+		latest := ""
+		if len(ret[i].Actors) > 0 {
+			latest = ret[i].Actors[0].Handle
+		}
+		ret[i].Summary = notifications.Summary(group.Type, latest, group.Count)
+		if group.ChirpID.Valid {
+			ret[i].ChirpID = &group.ChirpID.UUID
+		}
+		if group.ReportID.Valid {
+			ret[i].ReportID = &group.ReportID.UUID
+		}
+	}
+	return ret, nil
+}
+
+// notify records a notification, grouped with similar ones from the same
+// day. Nothing is recorded when the actor is the user being notified, when
+// either blocked the other or when the user muted the actor.
+func notify(ctx context.Context, queries *database.Queries, params database.CreateNotificationParams) error {
+	groupKey := notifications.GroupKey(params.Type, params.ChirpID, time.Now())
+	params.GroupKey = sql.NullString{String: groupKey, Valid: groupKey != ""}
+	_, err := queries.CreateNotification(ctx, params)
+	return err
+}
+
+// notifyQuoted tells the author of the chirp a quote chirp quotes about it,
+// if they can see the quote.
+func notifyQuoted(ctx context.Context, queries *database.Queries, chirp database.Chirp) error {
+	if !chirp.QuotedChirpID.Valid || chirp.IsRechirp {
+		return nil
+	}
+	quoted, err := queries.RetrieveChirpById(ctx, chirp.QuotedChirpID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !chirpVisibleTo(ctx, queries, chirp, uuid.NullUUID{UUID: quoted.UserID, Valid: true}) {
+		return nil
+	}
+	return notify(ctx, queries, database.CreateNotificationParams{
+		UserID:  quoted.UserID,
+		ActorID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Type:    notifications.TypeQuote,
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+}
+
+// streamCreatedChirp sends a chirp to the chirp streams once it's committed.
+// Every visibility is sent with the chirp's visibility and hashtags, the
+// subscribers decide who sees it.
+func streamCreatedChirp(ctx context.Context, config *utils.ApiConfig, chirpStream stream.Fanout, chirp database.Chirp) {
+	if chirp.PublishAt.Valid {
+		return
+	}
+	author, err := config.DbQueries.GetUserById(ctx, chirp.UserID)
+	if err != nil || author.ShadowBanned {
+		return
+	}
+	retChirps, err := chirpResponses(ctx, config, uuid.NullUUID{}, []database.Chirp{chirp})
+	if err != nil {
+		log.Printf("error building stream event for chirp %s: %v", chirp.ID, err)
+		return
+	}
+	data, err := json.Marshal(retChirps[0])
+	if err != nil {
+		log.Printf("error building stream event for chirp %s: %v", chirp.ID, err)
+		return
+	}
+	err = chirpStream.Publish(ctx, stream.Event{
+		Type:       stream.TypeCreated,
+		ChirpID:    chirp.ID,
+		AuthorID:   chirp.UserID,
+		Visibility: chirp.Visibility,
+		Tags:       hashtags.Extract(chirp.Body),
+		Data:       data,
+	})
+	if err != nil {
+		log.Printf("error streaming chirp %s: %v", chirp.ID, err)
+	}
+}
+
+// streamDeletedChirp tells the chirp streams a chirp they could have sent
+// is gone.
+func streamDeletedChirp(ctx context.Context, chirpStream stream.Fanout, chirp database.Chirp) {
+	if chirp.PublishAt.Valid {
+		return
+	}
+	data, err := json.Marshal(struct {
+		ID     uuid.UUID `json:"id"`
+		UserID uuid.UUID `json:"user_id"`
+	}{chirp.ID, chirp.UserID})
+	if err != nil {
+		return
+	}
+	err = chirpStream.Publish(ctx, stream.Event{
+		Type:       stream.TypeDeleted,
+		ChirpID:    chirp.ID,
+		AuthorID:   chirp.UserID,
+		Visibility: chirp.Visibility,
+		Tags:       hashtags.Extract(chirp.Body),
+		Data:       data,
+	})
+	if err != nil {
+		log.Printf("error streaming deletion of chirp %s: %v", chirp.ID, err)
+	}
+}
+
+// pollResponse is a poll as a viewer sees it. The vote counts are left out
+// until the viewer voted or the poll closed.
+type pollResponse struct {
+	ID            uuid.UUID            `json:"id"`
+	ChirpID       uuid.UUID            `json:"chirp_id"`
+	ClosesAt      time.Time            `json:"closes_at"`
+	Closed        bool                 `json:"closed"`
+	Options       []pollOptionResponse `json:"options"`
+	TotalVotes    *int64               `json:"total_votes,omitempty"`
+	VotedOptionID *uuid.UUID           `json:"voted_option_id,omitempty"`
+}
+
+type pollOptionResponse struct {
+	ID    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Votes *int64    `json:"votes,omitempty"`
+}
+
+// pollResponses builds the responses of polls as viewerID sees them, keyed
+// by chirp id, with a query for the options and one for the viewer's votes.
+func pollResponses(ctx context.Context, queries *database.Queries, viewerID uuid.NullUUID, chirpPolls []database.Poll) (map[uuid.UUID]pollResponse, error) {
+	ret := make(map[uuid.UUID]pollResponse, len(chirpPolls))
+	if len(chirpPolls) == 0 {
+		return ret, nil
+	}
+	pollIDs := make([]uuid.UUID, len(chirpPolls))
+	for i, poll := range chirpPolls {
+		pollIDs[i] = poll.ID
+	}
+	options, err := queries.RetrievePollOptionsWithVotes(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	byPoll := make(map[uuid.UUID][]database.RetrievePollOptionsWithVotesRow)
+	for _, option := range options {
+		byPoll[option.PollID] = append(byPoll[option.PollID], option)
+	}
+	voted := make(map[uuid.UUID]uuid.UUID)
+	if viewerID.Valid {
+		votes, err := queries.RetrievePollVotesByUser(ctx, database.RetrievePollVotesByUserParams{
+			UserID:  viewerID.UUID,
+			PollIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range votes {
+			voted[vote.PollID] = vote.OptionID
+		}
+	}
+	now := time.Now()
+	for _, poll := range chirpPolls {
+		retPoll := pollResponse{
+			ID:       poll.ID,
+			ChirpID:  poll.ChirpID,
+			ClosesAt: poll.ClosesAt,
+			Closed:   polls.Closed(poll.ClosesAt, now),
+			Options:  []pollOptionResponse{},
+		}
+		optionID, hasVoted := voted[poll.ID]
+		if hasVoted {
+			retPoll.VotedOptionID = &optionID
+		}
+		visible := polls.ResultsVisible(hasVoted, retPoll.Closed)
+		var total int64
+		for _, option := range byPoll[poll.ID] {
+			retOption := pollOptionResponse{ID: option.ID, Text: option.Text}
+			if visible {
+				votes := option.Votes
+				retOption.Votes = &votes
+			}
+			total += option.Votes
+			retPoll.Options = append(retPoll.Options, retOption)
+		}
+		if visible {
+			retPoll.TotalVotes = &total
+		}
+		ret[poll.ChirpID] = retPoll
 	}
+	return ret, nil
 }
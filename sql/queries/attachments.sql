@@ -0,0 +1,18 @@
+-- name: CreateChirpAttachment :one
+insert into chirp_attachments (id, chirp_id, position, storage_key, original_key, content_type, size_bytes, status, created_at) values ($1, $2, $3, $4, $5, $6, $7, 'processing', NOW()) returning *;
+-- name: RetrieveAttachmentsByChirpIds :many
+select * from chirp_attachments where chirp_id = any(sqlc.arg(chirp_ids)::uuid[]) order by chirp_id, position;
+-- name: ClaimPendingAttachment :one
+update chirp_attachments set attempts = attempts + 1, processing_started_at = NOW()
+where id = (
+    select id from chirp_attachments
+    where status = 'processing' and (processing_started_at is null or processing_started_at < NOW() - interval '5 minutes')
+    order by created_at
+    limit 1
+    for update skip locked
+)
+returning *;
+-- name: CompleteAttachment :execrows
+update chirp_attachments set status = 'ready', original_key = null, content_type = $2, size_bytes = $3, width = $4, height = $5, thumbnail_key = $6, blurhash = $7 where id = $1;
+-- name: FailAttachment :exec
+update chirp_attachments set status = 'failed', original_key = null where id = $1;
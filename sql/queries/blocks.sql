@@ -0,0 +1,22 @@
+-- name: CreateBlock :execrows
+insert into blocks (blocker_id, blocked_id, created_at) values ($1, $2, NOW()) on conflict do nothing;
+-- name: DeleteBlock :execrows
+delete from blocks where blocker_id = $1 and blocked_id = $2;
+-- name: GetBlocks :many
+select * from blocks where blocker_id = $1 order by created_at desc;
+-- name: IsBlockedBetween :one
+select exists (
+    select 1 from blocks
+    where (blocker_id = sqlc.arg(user_id) and blocked_id = sqlc.arg(other_id))
+       or (blocker_id = sqlc.arg(other_id) and blocked_id = sqlc.arg(user_id))
+);
+-- name: CreateMute :execrows
+insert into mutes (muter_id, muted_id, created_at) values ($1, $2, NOW()) on conflict do nothing;
+-- name: DeleteMute :execrows
+delete from mutes where muter_id = $1 and muted_id = $2;
+-- name: GetMutes :many
+select * from mutes where muter_id = $1 order by created_at desc;
+-- name: GetHiddenAuthorIds :many
+select blocks.blocked_id from blocks where blocks.blocker_id = $1
+union select blocks.blocker_id from blocks where blocks.blocked_id = $1
+union select mutes.muted_id from mutes where mutes.muter_id = $1;
@@ -0,0 +1,36 @@
+-- name: CreateBookmark :one
+insert into bookmarks (user_id, chirp_id, collection_id, created_at) values ($1, $2, $3, NOW())
+on conflict (user_id, chirp_id) do update set collection_id = excluded.collection_id
+returning *;
+-- name: DeleteBookmark :execrows
+delete from bookmarks where user_id = $1 and chirp_id = $2;
+-- name: DeleteBookmarksBetween :exec
+delete from bookmarks
+where (bookmarks.user_id = sqlc.arg(user_id) and chirp_id in (select id from chirps where chirps.user_id = sqlc.arg(other_id)))
+   or (bookmarks.user_id = sqlc.arg(other_id) and chirp_id in (select id from chirps where chirps.user_id = sqlc.arg(user_id)));
+-- name: RetrieveBookmarks :many
+select bookmarks.* from bookmarks
+join chirps on chirps.id = bookmarks.chirp_id
+join users on users.id = chirps.user_id
+where bookmarks.user_id = sqlc.arg(user_id)
+  and (not users.shadow_banned or chirps.user_id = sqlc.arg(user_id))
+  and (chirps.visibility <> 'followers_only' or chirps.user_id = sqlc.arg(user_id) or chirps.user_id in (select followee_id from follows where follower_id = sqlc.arg(user_id)))
+  and (sqlc.narg(collection_id)::uuid is null or bookmarks.collection_id = sqlc.narg(collection_id)::uuid)
+  and (not sqlc.arg(uncollected)::boolean or bookmarks.collection_id is null)
+  and (sqlc.narg(before_created_at)::timestamp is null or (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+order by bookmarks.created_at desc, bookmarks.chirp_id desc
+limit sqlc.arg(page_size);
+-- name: RetrieveBookmarkedAmongChirpIds :many
+select chirp_id from bookmarks where user_id = sqlc.arg(user_id) and chirp_id = any(sqlc.arg(chirp_ids)::uuid[]);
+-- name: CreateBookmarkCollection :one
+insert into bookmark_collections (id, user_id, name, created_at, updated_at) values (gen_random_uuid(), $1, $2, NOW(), NOW()) returning *;
+-- name: GetBookmarkCollections :many
+select * from bookmark_collections where user_id = $1 order by lower(name);
+-- name: GetBookmarkCollection :one
+select * from bookmark_collections where id = $1 and user_id = $2;
+-- name: CountBookmarkCollections :one
+select count(*) from bookmark_collections where user_id = $1;
+-- name: RenameBookmarkCollection :one
+update bookmark_collections set name = $3, updated_at = NOW() where id = $1 and user_id = $2 returning *;
+-- name: DeleteBookmarkCollection :execrows
+delete from bookmark_collections where id = $1 and user_id = $2;
@@ -1,14 +1,66 @@
 -- name: CreateChirp :one
-insert into chirps (id, created_at, updated_at, body, user_id) values (gen_random_uuid(), NOW(), NOW(), $1, $2) returning *;
+insert into chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, visibility) values (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5) returning *;
 -- name: RetrieveChirps :many
-select * from chirps order by created_at asc;
+select chirps.* from chirps join users on users.id = chirps.user_id
+where chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = sqlc.narg(viewer_id)::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = sqlc.narg(viewer_id)::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = sqlc.narg(viewer_id)::uuid))
+  and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.narg(viewer_id)::uuid)
+  and (chirps.visibility = 'public' or chirps.user_id = sqlc.narg(viewer_id)::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = sqlc.narg(viewer_id)::uuid)))
+order by chirps.created_at asc;
 -- name: RetrieveChirpsDesc :many
-select * from chirps order by created_at desc;
+select chirps.* from chirps join users on users.id = chirps.user_id
+where chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = sqlc.narg(viewer_id)::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = sqlc.narg(viewer_id)::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = sqlc.narg(viewer_id)::uuid))
+  and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.narg(viewer_id)::uuid)
+  and (chirps.visibility = 'public' or chirps.user_id = sqlc.narg(viewer_id)::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = sqlc.narg(viewer_id)::uuid)))
+order by chirps.created_at desc;
 -- name: RetrieveChirpById :one
+select * from chirps where id = $1 and publish_at is null;
+-- name: RetrieveChirpOrScheduledById :one
 select * from chirps where id = $1;
+-- name: RetrieveChirpsByIds :many
+select chirps.* from chirps join users on users.id = chirps.user_id
+where chirps.id = any(sqlc.arg(ids)::uuid[]) and chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = sqlc.narg(viewer_id)::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = sqlc.narg(viewer_id)::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = sqlc.narg(viewer_id)::uuid))
+  and (chirps.visibility <> 'followers_only' or chirps.user_id = sqlc.narg(viewer_id)::uuid or chirps.user_id in (select followee_id from follows where follower_id = sqlc.narg(viewer_id)::uuid));
 -- name: RetrieveChirpsByAuthor :many
-select * from chirps where user_id = $1 order by created_at asc;
+select chirps.* from chirps join users on users.id = chirps.user_id
+where chirps.user_id = sqlc.arg(user_id) and chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = sqlc.narg(viewer_id)::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = sqlc.narg(viewer_id)::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = sqlc.narg(viewer_id)::uuid))
+  and (chirps.visibility = 'public' or chirps.user_id = sqlc.narg(viewer_id)::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = sqlc.narg(viewer_id)::uuid)))
+order by chirps.created_at asc;
 -- name: RetrieveChirpsByAuthorDesc :many
-select * from chirps where user_id = $1 order by created_at desc;
+select chirps.* from chirps join users on users.id = chirps.user_id
+where chirps.user_id = sqlc.arg(user_id) and chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = sqlc.narg(viewer_id)::uuid)
+  and not exists (select 1 from blocks where (blocks.blocker_id = sqlc.narg(viewer_id)::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = sqlc.narg(viewer_id)::uuid))
+  and (chirps.visibility = 'public' or chirps.user_id = sqlc.narg(viewer_id)::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = sqlc.narg(viewer_id)::uuid)))
+order by chirps.created_at desc;
+-- name: RetrieveRechirpByUser :one
+select * from chirps where user_id = $1 and quoted_chirp_id = $2 and is_rechirp = true;
 -- name: DeleteChirpById :exec
-delete from chirps where id = $1 returning *;
\ No newline at end of file
+delete from chirps where id = $1 returning *;
+-- name: DeleteRechirpsOfChirp :exec
+delete from chirps where quoted_chirp_id = $1 and is_rechirp = true;
+-- name: CreateScheduledChirp :one
+insert into chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp, publish_at, visibility) values (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, false, $4, $5) returning *;
+-- name: RetrieveScheduledChirpsByAuthor :many
+select * from chirps where user_id = $1 and publish_at is not null order by publish_at asc, id asc;
+-- name: RescheduleChirp :one
+update chirps set publish_at = $3, publish_attempts = 0, next_publish_attempt_at = null, updated_at = NOW()
+where id = $1 and user_id = $2 and publish_at is not null
+returning *;
+-- name: CancelScheduledChirp :execrows
+delete from chirps where id = $1 and user_id = $2 and publish_at is not null;
+-- name: PublishDueChirp :one
+update chirps set publish_at = null, publish_attempts = 0, next_publish_attempt_at = null, created_at = NOW(), updated_at = NOW()
+where id = (
+    select id from chirps
+    where publish_at <= NOW() and (next_publish_attempt_at is null or next_publish_attempt_at <= NOW())
+    order by publish_at
+    limit 1
+    for update skip locked
+)
+returning *;
+-- name: PostponeScheduledChirp :exec
+update chirps set publish_attempts = publish_attempts + 1, next_publish_attempt_at = NOW() + make_interval(secs => sqlc.arg(delay_seconds)::integer)
+where id = sqlc.arg(id) and publish_at is not null;
@@ -0,0 +1,50 @@
+-- name: CreateConversation :one
+insert into conversations (id, created_at, updated_at, last_message_at) values (gen_random_uuid(), NOW(), NOW(), NOW()) returning *;
+-- name: AddConversationParticipant :exec
+insert into conversation_participants (conversation_id, user_id, joined_at) values ($1, $2, NOW()) on conflict do nothing;
+-- name: CreateDirectConversation :one
+insert into conversations (id, created_at, updated_at, last_message_at, direct_key)
+values (gen_random_uuid(), NOW(), NOW(), NOW(), least(sqlc.arg(user_id)::text, sqlc.arg(other_id)::text) || ':' || greatest(sqlc.arg(user_id)::text, sqlc.arg(other_id)::text))
+on conflict (direct_key) do nothing
+returning *;
+-- name: FindDirectConversation :one
+select * from conversations
+where direct_key = least(sqlc.arg(user_id)::text, sqlc.arg(other_id)::text) || ':' || greatest(sqlc.arg(user_id)::text, sqlc.arg(other_id)::text);
+-- name: GetConversationForParticipant :one
+select conversations.id, conversations.created_at, conversations.updated_at, conversations.last_message_at,
+  (select count(*) from messages where messages.conversation_id = conversations.id and messages.sender_id <> sqlc.arg(user_id) and (conversation_participants.last_read_at is null or messages.created_at > conversation_participants.last_read_at)) as unread_count
+from conversations join conversation_participants on conversation_participants.conversation_id = conversations.id
+where conversations.id = sqlc.arg(id) and conversation_participants.user_id = sqlc.arg(user_id);
+-- name: RetrieveConversations :many
+select conversations.id, conversations.created_at, conversations.updated_at, conversations.last_message_at,
+  (select count(*) from messages where messages.conversation_id = conversations.id and messages.sender_id <> sqlc.arg(user_id) and (conversation_participants.last_read_at is null or messages.created_at > conversation_participants.last_read_at)) as unread_count
+from conversations join conversation_participants on conversation_participants.conversation_id = conversations.id
+where conversation_participants.user_id = sqlc.arg(user_id)
+  and (sqlc.narg(before_last_message_at)::timestamp is null or (conversations.last_message_at, conversations.id) < (sqlc.narg(before_last_message_at)::timestamp, sqlc.narg(before_id)::uuid))
+order by conversations.last_message_at desc, conversations.id desc
+limit sqlc.arg(page_size);
+-- name: RetrieveParticipantsByConversationIds :many
+select conversation_participants.conversation_id, conversation_participants.user_id, users.handle
+from conversation_participants join users on users.id = conversation_participants.user_id
+where conversation_participants.conversation_id = any(sqlc.arg(conversation_ids)::uuid[])
+order by conversation_participants.conversation_id, conversation_participants.joined_at, conversation_participants.user_id;
+-- name: IsBlockedInConversation :one
+select exists (
+    select 1 from conversation_participants
+    join blocks on (blocks.blocker_id = sqlc.arg(user_id) and blocks.blocked_id = conversation_participants.user_id)
+                or (blocks.blocker_id = conversation_participants.user_id and blocks.blocked_id = sqlc.arg(user_id))
+    where conversation_participants.conversation_id = sqlc.arg(conversation_id)
+);
+-- name: CreateMessage :one
+insert into messages (id, conversation_id, sender_id, body, created_at) values (gen_random_uuid(), $1, $2, $3, NOW()) returning *;
+-- name: SetConversationLastMessage :exec
+update conversations set last_message_at = $2, updated_at = NOW() where id = $1;
+-- name: RetrieveMessages :many
+select * from messages
+where conversation_id = sqlc.arg(conversation_id)
+  and (sqlc.narg(before_created_at)::timestamp is null or (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
+-- name: MarkConversationRead :execrows
+update conversation_participants set last_read_at = NOW()
+where conversation_id = $1 and user_id = $2;
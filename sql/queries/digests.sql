@@ -0,0 +1,44 @@
+-- name: UpdateUserDigest :one
+update users set digest_frequency = $2, timezone = $3, updated_at = NOW() where id = $1 returning *;
+-- name: UnsubscribeUserFromDigest :execrows
+update users set digest_frequency = 'off', updated_at = NOW() where id = $1 and digest_frequency <> 'off';
+-- name: RetrieveDigestSubscribers :many
+select * from users
+where digest_frequency <> 'off' and (sqlc.narg(after_id)::uuid is null or id > sqlc.narg(after_id)::uuid)
+order by id
+limit sqlc.arg(page_size);
+-- name: ClaimEmailDigest :execrows
+insert into email_digests (user_id, frequency, period_start, created_at) values ($1, $2, $3, NOW()) on conflict do nothing;
+-- name: RetrieveDigestNewFollowers :many
+select users.id, users.handle, count(*) over () as total
+from follows join users on users.id = follows.follower_id
+where follows.followee_id = sqlc.arg(user_id)
+  and follows.created_at >= sqlc.arg(since) and follows.created_at < sqlc.arg(until)
+  and not users.shadow_banned
+  and users.id not in (select muted_id from mutes where muter_id = sqlc.arg(user_id))
+order by follows.created_at desc
+limit sqlc.arg(page_size);
+-- name: RetrieveDigestTopChirps :many
+select chirps.id, chirps.body, chirps.created_at, users.handle,
+  (select count(*) from chirps shares where shares.quoted_chirp_id = chirps.id) as shares
+from chirps
+join follows on follows.followee_id = chirps.user_id and follows.follower_id = sqlc.arg(user_id)
+join users on users.id = chirps.user_id
+where chirps.created_at >= sqlc.arg(since) and chirps.created_at < sqlc.arg(until)
+  and chirps.publish_at is null and not chirps.is_rechirp
+  and chirps.visibility in ('public', 'followers_only')
+  and not users.shadow_banned
+  and chirps.user_id not in (select blocked_id from blocks where blocker_id = sqlc.arg(user_id))
+  and chirps.user_id not in (select blocker_id from blocks where blocked_id = sqlc.arg(user_id))
+  and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.arg(user_id))
+order by shares desc, chirps.created_at desc, chirps.id desc
+limit sqlc.arg(page_size);
+-- name: RetrieveDigestUnreadMentions :many
+select chirps.id, chirps.body, chirps.created_at, users.handle, count(*) over () as total
+from notifications
+join chirps on chirps.id = notifications.chirp_id
+join users on users.id = chirps.user_id
+where notifications.user_id = sqlc.arg(user_id) and notifications.type = 'mention'
+  and notifications.read_at is null and notifications.created_at < sqlc.arg(until)
+order by notifications.created_at desc, notifications.id desc
+limit sqlc.arg(page_size);
@@ -0,0 +1,18 @@
+-- name: LockDrafts :exec
+select pg_advisory_xact_lock(hashtextextended('drafts:' || sqlc.arg(user_id)::text, 0));
+-- name: CreateDraft :one
+insert into drafts (id, user_id, body, quoted_chirp_id, created_at, updated_at)
+select gen_random_uuid(), sqlc.arg(user_id), sqlc.arg(body), sqlc.arg(quoted_chirp_id), NOW(), NOW()
+from drafts where user_id = sqlc.arg(user_id)
+having count(*) < sqlc.arg(max_drafts)::int
+returning *;
+-- name: GetDraft :one
+select * from drafts where id = $1 and user_id = $2;
+-- name: GetDraftsByUser :many
+select * from drafts where user_id = $1 order by updated_at desc, id desc;
+-- name: UpdateDraft :one
+update drafts set body = $3, quoted_chirp_id = $4, updated_at = NOW() where id = $1 and user_id = $2 returning *;
+-- name: DeleteDraft :execrows
+delete from drafts where id = $1 and user_id = $2;
+-- name: TakeDraft :one
+delete from drafts where id = $1 and user_id = $2 returning *;
@@ -0,0 +1,18 @@
+-- name: CreateFollow :execrows
+insert into follows (follower_id, followee_id, created_at) values ($1, $2, NOW()) on conflict do nothing;
+-- name: DeleteFollow :execrows
+delete from follows where follower_id = $1 and followee_id = $2;
+-- name: GetFollowers :many
+select * from follows where followee_id = $1 order by created_at desc;
+-- name: GetFollowing :many
+select * from follows where follower_id = $1 order by created_at desc;
+-- name: IsFollowing :one
+select exists (select 1 from follows where follower_id = $1 and followee_id = $2);
+-- name: CountFollowers :one
+select count(*) from follows where followee_id = $1;
+-- name: CountFollowing :one
+select count(*) from follows where follower_id = $1;
+-- name: CreateFollowEvent :one
+insert into follow_events (id, created_at, follower_id, followee_id, event) values (gen_random_uuid(), NOW(), $1, $2, $3) returning *;
+-- name: GetFollowEventsSince :many
+select * from follow_events where created_at > $1 order by created_at asc;
@@ -0,0 +1,16 @@
+-- name: CreateChirpMention :exec
+insert into chirp_mentions (chirp_id, user_id, start_offset, end_offset, created_at) values ($1, $2, $3, $4, $5) on conflict do nothing;
+-- name: RetrieveMentionsByChirpIds :many
+select chirp_mentions.chirp_id, chirp_mentions.user_id, chirp_mentions.start_offset, chirp_mentions.end_offset, users.handle
+from chirp_mentions join users on users.id = chirp_mentions.user_id
+where chirp_mentions.chirp_id = any(sqlc.arg(chirp_ids)::uuid[])
+order by chirp_mentions.chirp_id, chirp_mentions.start_offset;
+-- name: RetrieveChirpsMentioningUser :many
+select chirps.* from chirp_mentions join chirps on chirps.id = chirp_mentions.chirp_id join users on users.id = chirps.user_id
+where chirp_mentions.user_id = sqlc.arg(user_id) and (not users.shadow_banned or chirps.user_id = sqlc.arg(user_id))
+  and not exists (select 1 from blocks where (blocks.blocker_id = sqlc.arg(user_id) and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = sqlc.arg(user_id)))
+  and (chirps.visibility <> 'followers_only' or chirps.user_id = sqlc.arg(user_id) or chirps.user_id in (select followee_id from follows where follower_id = sqlc.arg(user_id)))
+  and (sqlc.narg(before_created_at)::timestamp is null or (chirps.created_at, chirps.id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+group by chirps.id
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg(page_size);
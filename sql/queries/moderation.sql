@@ -0,0 +1,17 @@
+-- name: GetModerationRules :many
+select * from moderation_rules order by lower(term);
+-- name: GetModerationRuleById :one
+select * from moderation_rules where id = $1;
+-- name: CreateModerationRule :one
+insert into moderation_rules (id, term, action, created_at, updated_at) values (gen_random_uuid(), $1, $2, NOW(), NOW()) returning *;
+-- name: UpdateModerationRule :one
+update moderation_rules set term = $2, action = $3, updated_at = NOW() where id = $1 returning *;
+-- name: DeleteModerationRule :execrows
+delete from moderation_rules where id = $1;
+-- name: CreateChirpFlag :exec
+insert into chirp_flags (id, chirp_id, rule_id, term, created_at) values (gen_random_uuid(), $1, $2, $3, NOW());
+-- name: RetrieveChirpFlags :many
+select * from chirp_flags
+where sqlc.narg(before_created_at)::timestamp is null or (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
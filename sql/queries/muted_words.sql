@@ -0,0 +1,19 @@
+-- name: GetActiveMutedWords :many
+select * from muted_words
+where user_id = $1 and (expires_at is null or expires_at > NOW())
+order by created_at desc;
+-- name: CountActiveMutedWords :one
+select count(*) from muted_words
+where user_id = $1 and (expires_at is null or expires_at > NOW());
+-- name: CreateMutedWord :one
+insert into muted_words (id, user_id, term, expires_at, created_at, updated_at)
+values (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
+returning *;
+-- name: UpdateMutedWord :one
+update muted_words set term = $3, expires_at = $4, updated_at = NOW()
+where id = $1 and user_id = $2
+returning *;
+-- name: DeleteMutedWord :execrows
+delete from muted_words where id = $1 and user_id = $2;
+-- name: DeleteExpiredMutedWords :exec
+delete from muted_words where user_id = $1 and expires_at <= NOW();
@@ -0,0 +1,44 @@
+-- name: CreateNotification :execrows
+with created as (
+    insert into notifications (id, user_id, actor_id, type, chirp_id, report_id, group_key, created_at)
+    select gen_random_uuid(), sqlc.arg(user_id)::uuid, sqlc.narg(actor_id)::uuid, sqlc.arg(type)::text, sqlc.narg(chirp_id)::uuid, sqlc.narg(report_id)::uuid, sqlc.narg(group_key)::text, NOW()
+    where not exists (select 1 from user_settings where user_settings.user_id = sqlc.arg(user_id)::uuid and sqlc.arg(type)::text = any(user_settings.in_app_disabled))
+    and (sqlc.narg(actor_id)::uuid is null or (
+        sqlc.narg(actor_id)::uuid <> sqlc.arg(user_id)::uuid
+        and not exists (select 1 from blocks where (blocks.blocker_id = sqlc.arg(user_id)::uuid and blocks.blocked_id = sqlc.narg(actor_id)::uuid) or (blocks.blocker_id = sqlc.narg(actor_id)::uuid and blocks.blocked_id = sqlc.arg(user_id)::uuid))
+        and not exists (select 1 from mutes where mutes.muter_id = sqlc.arg(user_id)::uuid and mutes.muted_id = sqlc.narg(actor_id)::uuid)
+    ))
+    returning id, user_id, type
+)
+select pg_notify('chirpy_notifications', json_build_object('id', id, 'user_id', user_id, 'type', type)::text) from created;
+-- name: RetrieveNotificationGroups :many
+select coalesce(group_key, id::text)::text as group_id, type, chirp_id, report_id,
+  max(created_at)::timestamp as latest_at,
+  (array_agg(id order by created_at desc, id desc))[1]::uuid as latest_id,
+  count(*) as count,
+  count(*) filter (where read_at is null) as unread_count
+from notifications
+where user_id = sqlc.arg(user_id)
+group by coalesce(group_key, id::text), type, chirp_id, report_id
+having sqlc.narg(before_latest_at)::timestamp is null
+  or (max(created_at), (array_agg(id order by created_at desc, id desc))[1]) < (sqlc.narg(before_latest_at)::timestamp, sqlc.narg(before_id)::uuid)
+order by latest_at desc, latest_id desc
+limit sqlc.arg(page_size);
+-- name: RetrieveNotificationActors :many
+select ranked.group_id, ranked.actor_id, users.handle from (
+    select coalesce(group_key, id::text)::text as group_id, actor_id, created_at,
+      row_number() over (partition by coalesce(group_key, id::text) order by created_at desc, id desc) as position
+    from notifications
+    where user_id = sqlc.arg(user_id) and actor_id is not null
+      and coalesce(group_key, id::text) = any(sqlc.arg(group_ids)::text[])
+) ranked join users on users.id = ranked.actor_id
+where ranked.position <= sqlc.arg(per_group)::bigint
+order by ranked.group_id, ranked.created_at desc;
+-- name: CountUnreadNotifications :one
+select count(*) from notifications where user_id = $1 and read_at is null;
+-- name: MarkNotificationsRead :execrows
+update notifications set read_at = NOW()
+where user_id = sqlc.arg(user_id) and read_at is null
+  and coalesce(group_key, id::text) = any(sqlc.arg(group_ids)::text[]);
+-- name: MarkAllNotificationsRead :execrows
+update notifications set read_at = NOW() where user_id = $1 and read_at is null;
@@ -0,0 +1,16 @@
+-- name: LockPinnedChirps :exec
+select pg_advisory_xact_lock(hashtextextended('pinned_chirps:' || sqlc.arg(user_id)::text, 0));
+-- name: PinChirp :execrows
+insert into pinned_chirps (user_id, chirp_id, position, created_at)
+select sqlc.arg(user_id), sqlc.arg(chirp_id), coalesce(max(position), -1) + 1, NOW()
+from pinned_chirps where user_id = sqlc.arg(user_id)
+having count(*) < sqlc.arg(max_pins)::int
+on conflict (user_id, chirp_id) do nothing;
+-- name: UnpinChirp :execrows
+delete from pinned_chirps where user_id = $1 and chirp_id = $2;
+-- name: GetPinnedChirpIds :many
+select chirp_id from pinned_chirps where user_id = $1 order by position asc;
+-- name: RetrievePinnedAmongChirpIds :many
+select chirp_id from pinned_chirps where chirp_id = any(sqlc.arg(chirp_ids)::uuid[]);
+-- name: SetPinPosition :execrows
+update pinned_chirps set position = $3 where user_id = $1 and chirp_id = $2;
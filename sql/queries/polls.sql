@@ -0,0 +1,23 @@
+-- name: CreatePoll :one
+insert into polls (id, chirp_id, closes_at, created_at) values (gen_random_uuid(), $1, $2, NOW()) returning *;
+-- name: CreatePollOption :one
+insert into poll_options (id, poll_id, position, text) values (gen_random_uuid(), $1, $2, $3) returning *;
+-- name: GetPollById :one
+select * from polls where id = $1;
+-- name: RetrievePollsByChirpIds :many
+select * from polls where chirp_id = any(sqlc.arg(chirp_ids)::uuid[]);
+-- name: RetrievePollOptionsWithVotes :many
+select poll_options.id, poll_options.poll_id, poll_options.position, poll_options.text, count(poll_votes.user_id) as votes
+from poll_options left join poll_votes on poll_votes.option_id = poll_options.id
+where poll_options.poll_id = any(sqlc.arg(poll_ids)::uuid[])
+group by poll_options.id
+order by poll_options.poll_id, poll_options.position;
+-- name: RetrievePollVotesByUser :many
+select poll_id, option_id from poll_votes
+where user_id = sqlc.arg(user_id) and poll_id = any(sqlc.arg(poll_ids)::uuid[]);
+-- name: CreatePollVote :execrows
+insert into poll_votes (poll_id, user_id, option_id, created_at)
+select polls.id, sqlc.arg(user_id), poll_options.id, NOW()
+from polls join poll_options on poll_options.poll_id = polls.id
+where polls.id = sqlc.arg(poll_id) and poll_options.id = sqlc.arg(option_id) and polls.closes_at > NOW()
+on conflict do nothing;
@@ -5,4 +5,6 @@ select * from refresh_tokens where token = $1;
 -- name: UpdateRefreshTokenByToken :exec
 update refresh_tokens set token = $1, created_at = $2, updated_at = NOW(), user_id = $3, expires_at = $4, revoked_at = $5 where user_id = $3;
 -- name: DeleteRefreshTokenByToken :exec
-delete from refresh_tokens where token = $1;
\ No newline at end of file
+delete from refresh_tokens where token = $1;
+-- name: RevokeRefreshTokensByUser :exec
+update refresh_tokens set revoked_at = NOW(), updated_at = NOW() where user_id = $1 and revoked_at is null;
@@ -0,0 +1,36 @@
+-- name: CreateReport :one
+insert into reports (id, created_at, updated_at, reporter_id, chirp_id, user_id, reason, details) values (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5) returning *;
+-- name: GetReportById :one
+select * from reports where id = $1;
+-- name: RetrieveReportsByReporter :many
+select * from reports
+where reporter_id = sqlc.arg(reporter_id)
+  and (sqlc.narg(before_created_at)::timestamp is null or (created_at, id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
+-- name: RetrieveReportQueue :many
+select * from reports
+where status = sqlc.arg(status)
+  and (sqlc.narg(reason)::text is null or reason = sqlc.narg(reason)::text)
+  and (sqlc.narg(target_type)::text is null or (sqlc.narg(target_type)::text = 'chirp') = (chirp_id is not null))
+  and (sqlc.narg(assignee_id)::uuid is null or assignee_id = sqlc.narg(assignee_id)::uuid)
+  and (not sqlc.arg(unassigned)::boolean or assignee_id is null)
+  and (sqlc.narg(after_created_at)::timestamp is null or (created_at, id) > (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::uuid))
+order by created_at asc, id asc
+limit sqlc.arg(page_size);
+-- name: AssignReport :one
+update reports set assignee_id = $2, updated_at = NOW() where id = $1 returning *;
+-- name: ResolveReport :one
+update reports set status = 'resolved', outcome = $2, resolved_by = $3, resolved_at = NOW(), updated_at = NOW() where id = $1 and status = 'open' returning *;
+-- name: ResolveReportsByChirp :many
+update reports set status = 'resolved', outcome = $2, resolved_by = $3, resolved_at = NOW(), updated_at = NOW() where chirp_id = $1 and status = 'open' returning *;
+-- name: ResolveReportsByUser :many
+update reports set status = 'resolved', outcome = $2, resolved_by = $3, resolved_at = NOW(), updated_at = NOW() where user_id = $1 and status = 'open' returning *;
+-- name: CreateModerationAction :one
+insert into moderation_actions (id, created_at, moderator_id, report_id, action, target_user_id, target_chirp_id, note) values (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6) returning *;
+-- name: SuspendUser :one
+update users set suspended_until = $2, suspension_reason = $3, updated_at = NOW() where id = $1 returning *;
+-- name: LiftSuspension :one
+update users set suspended_until = null, suspension_reason = '', updated_at = NOW() where id = $1 returning *;
+-- name: SetShadowBanned :one
+update users set shadow_banned = $2, updated_at = NOW() where id = $1 returning *;
@@ -0,0 +1,22 @@
+-- name: GetUserSettings :one
+select * from user_settings where user_id = $1;
+-- name: CreateDefaultUserSettings :exec
+insert into user_settings (user_id, updated_at) values ($1, NOW()) on conflict (user_id) do nothing;
+-- name: GetUserSettingsForUpdate :one
+select * from user_settings where user_id = $1 for update;
+-- name: UpsertUserSettings :one
+insert into user_settings (user_id, in_app_disabled, email_disabled, discoverable_by_email, mentions_from, messages_from, default_visibility, updated_at)
+values ($1, $2, $3, $4, $5, $6, $7, NOW())
+on conflict (user_id) do update set
+    in_app_disabled = excluded.in_app_disabled,
+    email_disabled = excluded.email_disabled,
+    discoverable_by_email = excluded.discoverable_by_email,
+    mentions_from = excluded.mentions_from,
+    messages_from = excluded.messages_from,
+    default_visibility = excluded.default_visibility,
+    updated_at = excluded.updated_at
+returning *;
+-- name: GetDiscoverableUserByEmail :one
+select users.* from users
+join user_settings on user_settings.user_id = users.id
+where lower(users.email) = lower(sqlc.arg(email)) and user_settings.discoverable_by_email;
@@ -0,0 +1,22 @@
+-- name: UpsertTag :one
+insert into tags (id, name, created_at) values (gen_random_uuid(), $1, NOW()) on conflict (name) do update set name = excluded.name returning *;
+-- name: GetTagByName :one
+select * from tags where name = $1;
+-- name: CreateChirpTag :exec
+insert into chirp_tags (chirp_id, tag_id, created_at) values ($1, $2, $3) on conflict do nothing;
+-- name: RetrieveChirpsByTag :many
+select chirps.* from chirp_tags join chirps on chirps.id = chirp_tags.chirp_id join users on users.id = chirps.user_id
+where chirp_tags.tag_id = sqlc.arg(tag_id) and (not users.shadow_banned or chirps.user_id = sqlc.narg(viewer_id)::uuid)
+  and (sqlc.narg(viewer_id)::uuid is null or (not exists (select 1 from blocks where (blocks.blocker_id = sqlc.narg(viewer_id)::uuid and blocks.blocked_id = chirps.user_id) or (blocks.blocker_id = chirps.user_id and blocks.blocked_id = sqlc.narg(viewer_id)::uuid)) and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.narg(viewer_id)::uuid)))
+  and (chirps.visibility = 'public' or chirps.user_id = sqlc.narg(viewer_id)::uuid or (chirps.visibility = 'followers_only' and chirps.user_id in (select followee_id from follows where follower_id = sqlc.narg(viewer_id)::uuid)))
+  and (sqlc.narg(before_created_at)::timestamp is null or (chirp_tags.created_at, chirp_tags.chirp_id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+order by chirp_tags.created_at desc, chirp_tags.chirp_id desc
+limit sqlc.arg(page_size);
+-- name: RetrieveTagUsesSince :many
+select chirp_tags.tag_id, tags.name, chirp_tags.created_at from chirp_tags join tags on tags.id = chirp_tags.tag_id where chirp_tags.created_at > $1;
+-- name: DeleteTrendingTags :exec
+delete from trending_tags;
+-- name: CreateTrendingTag :exec
+insert into trending_tags (tag_id, name, score, chirp_count, computed_at) values ($1, $2, $3, $4, $5);
+-- name: GetTrendingTags :many
+select * from trending_tags order by score desc, name asc;
@@ -0,0 +1,43 @@
+-- name: RetrieveTimelineFanOut :many
+select chirps.* from chirps join users on users.id = chirps.user_id
+where (chirps.user_id = sqlc.arg(user_id) or chirps.user_id in (select followee_id from follows where follower_id = sqlc.arg(user_id)))
+  and chirps.publish_at is null and (not users.shadow_banned or chirps.user_id = sqlc.arg(user_id))
+  and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.arg(user_id))
+  and (sqlc.narg(before_created_at)::timestamp is null or (chirps.created_at, chirps.id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg(page_size);
+-- name: RetrieveTimelinePrecomputed :many
+select chirps.* from timeline_entries join chirps on chirps.id = timeline_entries.chirp_id join users on users.id = chirps.user_id
+where timeline_entries.user_id = sqlc.arg(user_id) and (not users.shadow_banned or chirps.user_id = sqlc.arg(user_id))
+  and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.arg(user_id))
+  and (sqlc.narg(before_created_at)::timestamp is null or (timeline_entries.created_at, timeline_entries.chirp_id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid))
+order by timeline_entries.created_at desc, timeline_entries.chirp_id desc
+limit sqlc.arg(page_size);
+-- name: FanOutChirpToTimelines :exec
+insert into timeline_entries (user_id, chirp_id, created_at)
+select users.id, sqlc.arg(chirp_id)::uuid, sqlc.arg(created_at)::timestamp from users
+where users.precomputed_timeline
+  and (users.id = sqlc.arg(user_id) or (users.id in (select follower_id from follows where followee_id = sqlc.arg(user_id))
+    and not exists (select 1 from users authors where authors.id = sqlc.arg(user_id) and authors.shadow_banned)))
+on conflict do nothing;
+-- name: BackfillTimeline :exec
+insert into timeline_entries (user_id, chirp_id, created_at)
+select sqlc.arg(user_id)::uuid, id, created_at from chirps
+where (user_id = sqlc.arg(user_id) or user_id in (select followee_id from follows where follower_id = sqlc.arg(user_id)))
+  and publish_at is null
+order by created_at desc
+limit sqlc.arg(max_entries)
+on conflict do nothing;
+-- name: BackfillTimelineFromAuthor :exec
+insert into timeline_entries (user_id, chirp_id, created_at)
+select sqlc.arg(user_id)::uuid, id, created_at from chirps
+where user_id = sqlc.arg(author_id) and publish_at is null
+order by created_at desc
+limit sqlc.arg(max_entries)
+on conflict do nothing;
+-- name: DeleteTimelineEntriesFromAuthor :exec
+delete from timeline_entries
+where timeline_entries.user_id = sqlc.arg(user_id)
+  and chirp_id in (select id from chirps where chirps.user_id = sqlc.arg(author_id));
+-- name: SetPrecomputedTimeline :exec
+update users set precomputed_timeline = $2, updated_at = NOW() where id = $1;
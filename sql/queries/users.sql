@@ -1,5 +1,5 @@
 -- name: CreateUser :one
-insert into users (id, created_at, updated_at, email, hashed_password) values (gen_random_uuid(), NOW(), NOW(), $1, $2) returning *;
+insert into users (id, created_at, updated_at, email, hashed_password, handle) values (gen_random_uuid(), NOW(), NOW(), $1, $2, $3) returning *;
 -- name: EmptyUsersTable :exec
 delete from users;
 -- name: GetUserByEmail :one
@@ -7,4 +7,16 @@ select * from users where email = $1;
 -- name: UpdateUserByID :one
 update users set id = $1, created_at = $2, updated_at = NOW(), email = $3, hashed_password = $4, is_chirpy_red = $5 where id = $1 returning *;
 -- name: GetUserById :one
-select * from users where id = $1;
\ No newline at end of file
+select * from users where id = $1;
+-- name: DeleteUserById :exec
+delete from users where id = $1;
+-- name: GetUsersByHandles :many
+select * from users where lower(handle) = any(sqlc.arg(handles)::text[]);
+-- name: GetUserByHandle :one
+select * from users where lower(handle) = lower(sqlc.arg(handle));
+-- name: UpdateUserProfile :one
+update users set handle = $2, display_name = $3, bio = $4, avatar_url = $5, updated_at = NOW() where id = $1 returning *;
+-- name: CreateHandleHistory :exec
+insert into handle_history (id, user_id, handle, created_at) values (gen_random_uuid(), $1, $2, NOW());
+-- name: GetLatestHandleHistory :one
+select * from handle_history where lower(handle) = lower(sqlc.arg(handle)) order by created_at desc limit 1;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table chirps add quoted_chirp_id uuid;
+alter table chirps add is_rechirp boolean not null default false;
+create index chirps_quoted_chirp_id_idx on chirps (quoted_chirp_id);
+create unique index chirps_user_id_rechirp_idx on chirps (user_id, quoted_chirp_id) where is_rechirp;
+
+-- +goose Down
+drop index chirps_user_id_rechirp_idx;
+drop index chirps_quoted_chirp_id_idx;
+alter table chirps drop column is_rechirp;
+alter table chirps drop column quoted_chirp_id;
@@ -0,0 +1,26 @@
+-- +goose Up
+create table follows (
+    follower_id uuid not null,
+    followee_id uuid not null,
+    created_at timestamp not null,
+    primary key (follower_id, followee_id),
+    foreign key (follower_id) references users(id) on delete cascade,
+    foreign key (followee_id) references users(id) on delete cascade,
+    check (follower_id <> followee_id)
+);
+create index follows_followee_id_idx on follows (followee_id, follower_id);
+
+create table follow_events (
+    id uuid primary key,
+    created_at timestamp not null,
+    follower_id uuid not null,
+    followee_id uuid not null,
+    event text not null check (event in ('follow', 'unfollow')),
+    foreign key (follower_id) references users(id) on delete cascade,
+    foreign key (followee_id) references users(id) on delete cascade
+);
+create index follow_events_created_at_idx on follow_events (created_at);
+
+-- +goose Down
+drop table follow_events;
+drop table follows;
@@ -0,0 +1,17 @@
+-- +goose Up
+alter table users add precomputed_timeline boolean not null default false;
+create table timeline_entries (
+    user_id uuid not null,
+    chirp_id uuid not null,
+    created_at timestamp not null,
+    primary key (user_id, chirp_id),
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (chirp_id) references chirps(id) on delete cascade
+);
+create index timeline_entries_user_id_created_at_idx on timeline_entries (user_id, created_at desc, chirp_id desc);
+create index chirps_user_id_created_at_idx on chirps (user_id, created_at desc, id desc);
+
+-- +goose Down
+drop index chirps_user_id_created_at_idx;
+drop table timeline_entries;
+alter table users drop column precomputed_timeline;
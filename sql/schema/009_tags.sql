@@ -0,0 +1,31 @@
+-- +goose Up
+create table tags (
+    id uuid primary key,
+    name text not null unique,
+    created_at timestamp not null
+);
+
+create table chirp_tags (
+    chirp_id uuid not null,
+    tag_id uuid not null,
+    created_at timestamp not null,
+    primary key (chirp_id, tag_id),
+    foreign key (chirp_id) references chirps(id) on delete cascade,
+    foreign key (tag_id) references tags(id) on delete cascade
+);
+create index chirp_tags_tag_id_created_at_idx on chirp_tags (tag_id, created_at desc, chirp_id desc);
+create index chirp_tags_created_at_idx on chirp_tags (created_at);
+
+create table trending_tags (
+    tag_id uuid primary key,
+    name text not null,
+    score double precision not null,
+    chirp_count integer not null,
+    computed_at timestamp not null,
+    foreign key (tag_id) references tags(id) on delete cascade
+);
+
+-- +goose Down
+drop table trending_tags;
+drop table chirp_tags;
+drop table tags;
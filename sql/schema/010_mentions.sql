@@ -0,0 +1,20 @@
+-- +goose Up
+alter table users add handle text;
+create unique index users_handle_idx on users (lower(handle));
+
+create table chirp_mentions (
+    chirp_id uuid not null,
+    user_id uuid not null,
+    start_offset integer not null,
+    end_offset integer not null,
+    created_at timestamp not null,
+    primary key (chirp_id, start_offset),
+    foreign key (chirp_id) references chirps(id) on delete cascade,
+    foreign key (user_id) references users(id) on delete cascade
+);
+create index chirp_mentions_user_id_idx on chirp_mentions (user_id, created_at desc);
+
+-- +goose Down
+drop table chirp_mentions;
+drop index users_handle_idx;
+alter table users drop column handle;
@@ -0,0 +1,19 @@
+-- +goose Up
+alter table users add display_name text not null default '';
+alter table users add bio text not null default '';
+alter table users add avatar_url text not null default '';
+
+create table handle_history (
+    id uuid primary key,
+    user_id uuid not null,
+    handle text not null,
+    created_at timestamp not null,
+    foreign key (user_id) references users(id) on delete cascade
+);
+create index handle_history_handle_idx on handle_history (lower(handle), created_at desc);
+
+-- +goose Down
+drop table handle_history;
+alter table users drop column avatar_url;
+alter table users drop column bio;
+alter table users drop column display_name;
@@ -0,0 +1,15 @@
+-- +goose Up
+create table chirp_attachments (
+    id uuid primary key,
+    chirp_id uuid not null,
+    position integer not null,
+    storage_key text not null,
+    content_type text not null,
+    size_bytes bigint not null,
+    created_at timestamp not null,
+    foreign key (chirp_id) references chirps(id) on delete cascade
+);
+create index chirp_attachments_chirp_id_idx on chirp_attachments (chirp_id, position);
+
+-- +goose Down
+drop table chirp_attachments;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- attachments stored before processing existed still carry their metadata,
+-- queue them up again with their current file as the original
+alter table chirp_attachments
+    add column status text not null default 'processing' check (status in ('processing', 'ready', 'failed')),
+    add column original_key text,
+    add column width integer not null default 0,
+    add column height integer not null default 0,
+    add column thumbnail_key text not null default '',
+    add column blurhash text not null default '',
+    add column attempts integer not null default 0,
+    add column processing_started_at timestamp;
+update chirp_attachments set original_key = storage_key;
+create index chirp_attachments_processing_idx on chirp_attachments (created_at) where status = 'processing';
+
+-- +goose Down
+drop index chirp_attachments_processing_idx;
+alter table chirp_attachments
+    drop column status,
+    drop column original_key,
+    drop column width,
+    drop column height,
+    drop column thumbnail_key,
+    drop column blurhash,
+    drop column attempts,
+    drop column processing_started_at;
@@ -0,0 +1,32 @@
+-- +goose Up
+create table moderation_rules (
+    id uuid primary key,
+    term text not null,
+    action text not null check (action in ('mask', 'reject', 'flag')),
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
+create unique index moderation_rules_term_idx on moderation_rules (lower(term));
+-- the words POST /api/chirps used to mask
+insert into moderation_rules (id, term, action, created_at, updated_at) values
+    (gen_random_uuid(), 'kerfuffle', 'mask', NOW(), NOW()),
+    (gen_random_uuid(), 'sharbert', 'mask', NOW(), NOW()),
+    (gen_random_uuid(), 'fornax', 'mask', NOW(), NOW());
+
+-- chirps that matched a flag rule, waiting for a moderator
+create table chirp_flags (
+    id uuid primary key,
+    chirp_id uuid not null,
+    rule_id uuid,
+    term text not null,
+    created_at timestamp not null,
+    foreign key (chirp_id) references chirps(id) on delete cascade
+);
+create index chirp_flags_created_at_idx on chirp_flags (created_at);
+
+alter table users add column is_moderator boolean not null default false;
+
+-- +goose Down
+alter table users drop column is_moderator;
+drop table chirp_flags;
+drop table moderation_rules;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- user_id is the reported user, the author of the chirp for chirp reports.
+-- The reported chirp and user aren't foreign keys so reports outlive them.
+create table reports (
+    id uuid primary key,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    reporter_id uuid not null,
+    chirp_id uuid,
+    user_id uuid not null,
+    reason text not null,
+    details text not null default '',
+    status text not null default 'open' check (status in ('open', 'resolved')),
+    assignee_id uuid,
+    outcome text check (outcome in ('dismissed', 'chirp_deleted', 'user_suspended')),
+    resolved_by uuid,
+    resolved_at timestamp,
+    foreign key (reporter_id) references users(id) on delete cascade,
+    foreign key (assignee_id) references users(id) on delete set null
+);
+create index reports_queue_idx on reports (status, created_at, id);
+create index reports_reporter_idx on reports (reporter_id, created_at);
+create index reports_chirp_id_idx on reports (chirp_id) where status = 'open';
+create index reports_user_id_idx on reports (user_id) where status = 'open';
+-- one open report per reporter and target
+create unique index reports_open_target_idx on reports (reporter_id, user_id, coalesce(chirp_id, '00000000-0000-0000-0000-000000000000')) where status = 'open';
+
+-- every action a moderator takes, kept when the report goes away
+create table moderation_actions (
+    id uuid primary key,
+    created_at timestamp not null,
+    moderator_id uuid not null,
+    report_id uuid,
+    action text not null,
+    target_user_id uuid,
+    target_chirp_id uuid,
+    note text not null default ''
+);
+create index moderation_actions_created_at_idx on moderation_actions (created_at);
+
+alter table users add column suspended_until timestamp;
+
+-- +goose Down
+alter table users drop column suspended_until;
+drop table moderation_actions;
+drop table reports;
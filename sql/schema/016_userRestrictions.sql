@@ -0,0 +1,9 @@
+-- +goose Up
+alter table users
+    add column suspension_reason text not null default '',
+    add column shadow_banned boolean not null default false;
+
+-- +goose Down
+alter table users
+    drop column suspension_reason,
+    drop column shadow_banned;
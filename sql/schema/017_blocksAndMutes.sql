@@ -0,0 +1,25 @@
+-- +goose Up
+create table blocks (
+    blocker_id uuid not null,
+    blocked_id uuid not null,
+    created_at timestamp not null,
+    primary key (blocker_id, blocked_id),
+    check (blocker_id <> blocked_id),
+    foreign key (blocker_id) references users(id) on delete cascade,
+    foreign key (blocked_id) references users(id) on delete cascade
+);
+create index blocks_blocked_id_idx on blocks (blocked_id);
+
+create table mutes (
+    muter_id uuid not null,
+    muted_id uuid not null,
+    created_at timestamp not null,
+    primary key (muter_id, muted_id),
+    check (muter_id <> muted_id),
+    foreign key (muter_id) references users(id) on delete cascade,
+    foreign key (muted_id) references users(id) on delete cascade
+);
+
+-- +goose Down
+drop table mutes;
+drop table blocks;
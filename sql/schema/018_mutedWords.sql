@@ -0,0 +1,15 @@
+-- +goose Up
+create table muted_words (
+    id uuid primary key,
+    user_id uuid not null,
+    term text not null,
+    -- null mutes the term until it's deleted
+    expires_at timestamp,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    foreign key (user_id) references users(id) on delete cascade
+);
+create unique index muted_words_user_term_idx on muted_words (user_id, lower(term));
+
+-- +goose Down
+drop table muted_words;
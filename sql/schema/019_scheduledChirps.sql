@@ -0,0 +1,9 @@
+-- +goose Up
+-- a chirp with publish_at set is scheduled, nobody but its author sees it
+-- until the scheduler clears publish_at
+alter table chirps add column publish_at timestamp;
+create index chirps_publish_at_idx on chirps (publish_at) where publish_at is not null;
+
+-- +goose Down
+drop index chirps_publish_at_idx;
+alter table chirps drop column publish_at;
@@ -0,0 +1,14 @@
+-- +goose Up
+create table drafts (
+    id uuid primary key,
+    user_id uuid not null,
+    body text not null,
+    quoted_chirp_id uuid,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    foreign key (user_id) references users(id) on delete cascade
+);
+create index drafts_user_id_idx on drafts (user_id, updated_at);
+
+-- +goose Down
+drop table drafts;
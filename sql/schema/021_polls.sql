@@ -0,0 +1,36 @@
+-- +goose Up
+create table polls (
+    id uuid primary key,
+    chirp_id uuid not null unique,
+    -- a poll is closed once closes_at has passed, nothing updates it
+    closes_at timestamp not null,
+    created_at timestamp not null,
+    foreign key (chirp_id) references chirps(id) on delete cascade
+);
+
+create table poll_options (
+    id uuid primary key,
+    poll_id uuid not null,
+    position integer not null,
+    text text not null,
+    unique (poll_id, position),
+    foreign key (poll_id) references polls(id) on delete cascade
+);
+
+-- one vote per user and poll, votes can't be changed
+create table poll_votes (
+    poll_id uuid not null,
+    user_id uuid not null,
+    option_id uuid not null,
+    created_at timestamp not null,
+    primary key (poll_id, user_id),
+    foreign key (poll_id) references polls(id) on delete cascade,
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (option_id) references poll_options(id) on delete cascade
+);
+create index poll_votes_option_id_idx on poll_votes (option_id);
+
+-- +goose Down
+drop table poll_votes;
+drop table poll_options;
+drop table polls;
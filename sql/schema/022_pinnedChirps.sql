@@ -0,0 +1,15 @@
+-- +goose Up
+-- deleting a chirp unpins it through the foreign key
+create table pinned_chirps (
+    user_id uuid not null,
+    chirp_id uuid not null,
+    position integer not null,
+    created_at timestamp not null,
+    primary key (user_id, chirp_id),
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (chirp_id) references chirps(id) on delete cascade
+);
+create index pinned_chirps_chirp_id_idx on pinned_chirps (chirp_id);
+
+-- +goose Down
+drop table pinned_chirps;
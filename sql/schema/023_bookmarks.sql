@@ -0,0 +1,29 @@
+-- +goose Up
+create table bookmark_collections (
+    id uuid primary key,
+    user_id uuid not null,
+    name text not null,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    foreign key (user_id) references users(id) on delete cascade
+);
+create unique index bookmark_collections_user_name_idx on bookmark_collections (user_id, lower(name));
+
+-- deleting a chirp deletes its bookmarks, deleting a collection keeps its
+-- bookmarks outside of any collection
+create table bookmarks (
+    user_id uuid not null,
+    chirp_id uuid not null,
+    collection_id uuid,
+    created_at timestamp not null,
+    primary key (user_id, chirp_id),
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (chirp_id) references chirps(id) on delete cascade,
+    foreign key (collection_id) references bookmark_collections(id) on delete set null
+);
+create index bookmarks_user_created_at_idx on bookmarks (user_id, created_at);
+create index bookmarks_chirp_id_idx on bookmarks (chirp_id);
+
+-- +goose Down
+drop table bookmarks;
+drop table bookmark_collections;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- followers_only chirps are seen by the author's followers, unlisted ones by
+-- anyone with their id but not in listings
+alter table chirps add column visibility text not null default 'public'
+    check (visibility in ('public', 'followers_only', 'unlisted'));
+
+-- +goose Down
+alter table chirps drop column visibility;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- last_message_at orders a user's conversations, a participant's messages
+-- after their last_read_at are unread
+create table conversations (
+    id uuid primary key,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    last_message_at timestamp not null
+);
+create table conversation_participants (
+    conversation_id uuid not null,
+    user_id uuid not null,
+    joined_at timestamp not null,
+    last_read_at timestamp,
+    primary key (conversation_id, user_id),
+    foreign key (conversation_id) references conversations(id) on delete cascade,
+    foreign key (user_id) references users(id) on delete cascade
+);
+create index conversation_participants_user_id_idx on conversation_participants (user_id);
+create table messages (
+    id uuid primary key,
+    conversation_id uuid not null,
+    sender_id uuid not null,
+    body text not null,
+    created_at timestamp not null,
+    foreign key (conversation_id) references conversations(id) on delete cascade,
+    foreign key (sender_id) references users(id) on delete cascade
+);
+create index messages_conversation_id_created_at_idx on messages (conversation_id, created_at, id);
+
+-- +goose Down
+drop table messages;
+drop table conversation_participants;
+drop table conversations;
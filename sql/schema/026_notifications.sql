@@ -0,0 +1,23 @@
+-- +goose Up
+-- notifications with the same group_key are shown together, the others one
+-- by one. A notification goes with the chirp or report it's about.
+create table notifications (
+    id uuid primary key,
+    user_id uuid not null,
+    actor_id uuid,
+    type text not null,
+    chirp_id uuid,
+    report_id uuid,
+    group_key text,
+    created_at timestamp not null,
+    read_at timestamp,
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (actor_id) references users(id) on delete cascade,
+    foreign key (chirp_id) references chirps(id) on delete cascade,
+    foreign key (report_id) references reports(id) on delete cascade
+);
+create index notifications_user_id_created_at_idx on notifications (user_id, created_at desc);
+create index notifications_unread_idx on notifications (user_id) where read_at is null;
+
+-- +goose Down
+drop table notifications;
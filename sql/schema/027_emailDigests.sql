@@ -0,0 +1,19 @@
+-- +goose Up
+-- digests are opt-in and cover the last full day or week in the user's
+-- timezone. A row in email_digests claims a user's period so it's sent once.
+alter table users add column digest_frequency text not null default 'off'
+    check (digest_frequency in ('off', 'daily', 'weekly'));
+alter table users add column timezone text not null default 'UTC';
+create table email_digests (
+    user_id uuid not null,
+    frequency text not null,
+    period_start date not null,
+    created_at timestamp not null,
+    primary key (user_id, frequency, period_start),
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+-- +goose Down
+drop table email_digests;
+alter table users drop column timezone;
+alter table users drop column digest_frequency;
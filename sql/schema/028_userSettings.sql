@@ -0,0 +1,20 @@
+-- +goose Up
+-- users without a row have the default settings. Notification types are
+-- listed when they're turned off, so new types are on for everyone.
+create table user_settings (
+    user_id uuid primary key,
+    in_app_disabled text[] not null default '{}',
+    email_disabled text[] not null default '{}',
+    discoverable_by_email boolean not null default false,
+    mentions_from text not null default 'everyone'
+        check (mentions_from in ('everyone', 'following', 'nobody')),
+    messages_from text not null default 'everyone'
+        check (messages_from in ('everyone', 'following', 'nobody')),
+    default_visibility text not null default 'public'
+        check (default_visibility in ('public', 'followers_only', 'unlisted')),
+    updated_at timestamp not null,
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+-- +goose Down
+drop table user_settings;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- a scheduled chirp that failed to publish is put off until
+-- next_publish_attempt_at, so it doesn't hold up the chirps due after it
+alter table chirps add column publish_attempts integer not null default 0;
+alter table chirps add column next_publish_attempt_at timestamp;
+
+-- +goose Down
+alter table chirps drop column next_publish_attempt_at;
+alter table chirps drop column publish_attempts;
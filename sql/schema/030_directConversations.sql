@@ -0,0 +1,21 @@
+-- +goose Up
+-- direct_key is the sorted pair of user ids of a two person conversation,
+-- being unique there's one conversation per pair however many requests
+-- start it at once
+alter table conversations add column direct_key text unique;
+update conversations set direct_key = pairs.direct_key
+from (
+    select distinct on (pair.direct_key) pair.conversation_id, pair.direct_key
+    from (
+        select conversation_id, min(user_id::text) || ':' || max(user_id::text) as direct_key
+        from conversation_participants
+        group by conversation_id
+        having count(*) = 2
+    ) pair
+    join conversations on conversations.id = pair.conversation_id
+    order by pair.direct_key, conversations.created_at asc
+) pairs
+where conversations.id = pairs.conversation_id;
+
+-- +goose Down
+alter table conversations drop column direct_key;
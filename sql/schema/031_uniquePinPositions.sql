@@ -0,0 +1,12 @@
+-- +goose Up
+-- deferred so reordering can swap positions within a transaction
+update pinned_chirps set position = ranked.position
+from (
+    select user_id, chirp_id, row_number() over (partition by user_id order by position, created_at) - 1 as position
+    from pinned_chirps
+) ranked
+where pinned_chirps.user_id = ranked.user_id and pinned_chirps.chirp_id = ranked.chirp_id;
+alter table pinned_chirps add constraint pinned_chirps_user_id_position_key unique (user_id, position) deferrable initially deferred;
+
+-- +goose Down
+alter table pinned_chirps drop constraint pinned_chirps_user_id_position_key;